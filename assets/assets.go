@@ -0,0 +1,16 @@
+// assets/assets.go
+package assets
+
+import "embed"
+
+// EmptyNodeProp is the known-good .empty.nodeprop.yml template shipped with the binary.
+// It is used to detect and optionally replace a broken on-disk template.
+//
+//go:embed .empty.nodeprop.yml
+var EmptyNodeProp []byte
+
+// Workflows holds the starter workflow templates shipped with the binary, selectable by name
+// (the file name without .yml) through --template.
+//
+//go:embed workflows/*.yml
+var Workflows embed.FS
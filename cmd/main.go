@@ -2,16 +2,27 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/Cdaprod/nodeprop/pkg/nodeprop" // Correct import path
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
 )
 
 // SignalHandler defines the structure for receiving signals to trigger actions.
@@ -59,19 +70,22 @@ func DynamicRunner[T any](fn func(T) error, arg T, logger *logrus.Logger) {
 	}()
 }
 
-// handleArgsOrSignals dynamically processes actions with generic argument types.
-func handleArgsOrSignals[T any](np *nodeprop.NodePropManager, action string, arg T, logger *logrus.Logger) {
+// handleArgsOrSignals processes an action requested via CLI or signal with the CLI arguments.
+func handleArgsOrSignals(np *nodeprop.NodePropManager, action string, arg nodeprop.NodePropArguments, logger *logrus.Logger) {
 	switch action {
 	case "add_workflow":
 		// Add workflow using dynamic arguments passed via CLI or signal
-		DynamicRunner(np.AddWorkflow, arg, logger) // Run in a Go routine
+		DynamicRunner(func(arg nodeprop.NodePropArguments) error {
+			defer printPlan(np)
+			return np.AddWorkflow(arg)
+		}, arg, logger) // Run in a Go routine
 	case "shutdown":
 		logger.Info("Shutting down NodePropManager...")
-		DynamicRunner(func(_ T) error {
+		DynamicRunner(func(_ nodeprop.NodePropArguments) error {
 			time.Sleep(1 * time.Second) // Simulate some work
 			fmt.Println("NodePropManager shutdown complete")
 			return nil
-		}, arg, logger) // Use empty struct or appropriate type as no argument is needed
+		}, arg, logger)
 	case "reload":
 		logger.Info("Reloading configuration...")
 		DynamicRunner(np.ReloadConfig, arg, logger)
@@ -80,6 +94,35 @@ func handleArgsOrSignals[T any](np *nodeprop.NodePropManager, action string, arg
 	}
 }
 
+// printPlan prints the changes recorded during a --dry-run.
+func printPlan(np *nodeprop.NodePropManager) {
+	plan, ok := np.Files.(*nodeprop.RecordingFS)
+	if !np.DryRun || !ok {
+		return
+	}
+	for _, change := range plan.Changes() {
+		fmt.Printf("would %s %s\n", change.Kind, change.Path)
+	}
+}
+
+// interactive reports whether standard input is a terminal.
+func interactive() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm asks a yes/no question on the terminal and reports whether it was answered yes.
+// It returns false without asking when standard input is not a terminal.
+func confirm(question string) bool {
+	if !interactive() {
+		return false
+	}
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
 // NodePropArguments holds dynamic arguments for generic actions.
 type NodePropArguments struct {
 	RepoPath string
@@ -95,12 +138,54 @@ func main() {
 	addWorkflow := flag.Bool("add-workflow", false, "Flag to add a new workflow")
 	repoPath := flag.String("repo", "", "Path to the target repository")
 	workflowName := flag.String("workflow", "", "Name of the workflow to add")
+	domain := flag.String("domain", "", "Domain of the service (default from the owner profile in owners.<owner>.domain)")
+	nodePropSubPath := flag.String("path", "", "Subdirectory of the repository to generate .nodeprop.yml in (monorepos)")
+	requireFile := flag.String("require-file", "", "Only add the workflow when this file exists in the repository")
+	skipIfFile := flag.String("skip-if-file", "", "Skip adding the workflow when this file exists in the repository")
+	workflowTemplate := flag.String("template", "", "Named workflow template to add instead of workflow_template_path, e.g. go-ci (see --list-templates)")
+	fromFile := flag.String("from-file", "", "Workflow file to add instead of a template")
+	fromURL := flag.String("from-url", "", "https:// URL of a workflow to add instead of a template, e.g. a raw gist URL")
+	listTemplates := flag.Bool("list-templates", false, "List the embedded and user workflow templates and exit")
+	workflowDir := flag.String("workflow-dir", "", "Directory of the repository to write the workflow to (default .github/workflows)")
+	signPath := flag.String("sign", "", "Sign the given .nodeprop.yml file and exit")
+	verifyPath := flag.String("verify", "", "Verify the signature of the given .nodeprop.yml file and exit")
+	provenancePath := flag.String("provenance", "", "Print the generated_by block of the given .nodeprop.yml file and exit")
+	graphRoot := flag.String("graph", "", "Print the dependency graph of every .nodeprop.yml under this directory and exit")
+	graphFormat := flag.String("graph-format", "dot", "Dependency graph format: dot or mermaid")
+	analyze := flag.String("analyze", "", "Comma-separated analyzers to run over --fleet (e.g. ports), or all; prints findings as JSON and exits")
+	fleetRoot := flag.String("fleet", ".", "Directory of checked-out repositories to analyze")
+	discoveryGlob := flag.String("discovery-glob", "", "Glob of the nodeprop files to load for --graph and --analyze, e.g. 'services/*/.nodeprop.yml' (default from discovery_glob)")
+	fleetExclude := flag.String("exclude", "", "Comma-separated repository names or glob patterns to leave out of --graph and --analyze")
+	includeArchived := flag.Bool("include-archived", false, "Include services with status archived in --graph and --analyze")
+	fleetFilter := flag.String("filter", "", "Only include nodeprop files matching this expression in --graph and --analyze, e.g. 'status == \"active\" && stars > 10'")
+	checkDNS := flag.Bool("dns", false, "Resolve each domain during --analyze domains and compare it with domains.expected_targets")
+	badgeMarkdown := flag.Bool("badge-md", false, "Print shields.io badge markdown for every workflow of --repo and exit")
+	listWorkflows := flag.Bool("list-workflows", false, "List the workflows of --repo with their deprecation state and exit")
+	deprecate := flag.Bool("deprecate", false, "Deprecate --workflow of --repo until --sunset and exit")
+	sunset := flag.String("sunset", "", "Sunset date (YYYY-MM-DD) of a workflow deprecated with --deprecate")
+	deleteNodeProp := flag.Bool("delete", false, "Delete the .nodeprop.yml of --repo (or --repo/--path) and exit; requires --yes")
+	confirmed := flag.Bool("yes", false, "Confirm destructive operations such as --delete")
+	eventFormat := flag.String("events", "", "Print manager events to stdout instead of logging them: jsonl prints one JSON object per line")
+	eventTypeList := flag.String("event-types", "", "Comma-separated event types to report: success, error, info (default all)")
+	showDiff := flag.Bool("diff", false, "Print a unified diff of every file before it is written")
+	confirmChanges := flag.Bool("confirm", false, "With --diff, ask before writing each changed file when run interactively")
+	dryRun := flag.Bool("dry-run", false, "Print the files that would be written or removed instead of changing anything")
+	outputFormat := flag.String("format", "", "Render each result of --list-templates, --badge-md, --list-workflows or --analyze through a Go template, e.g. 'tmpl={{.Name}}'")
 	configPath := flag.String("config", "config.yaml", "Path to the configuration file")
 	flag.Parse()
 
+	var outputTemplate *template.Template
+	if *outputFormat != "" {
+		var err error
+		if outputTemplate, err = nodeprop.ParseOutputTemplate(*outputFormat); err != nil {
+			logger.Fatalf("%v", err)
+		}
+	}
+
 	// Initialize Viper for configuration management
 	viper.SetConfigFile(*configPath)
 	viper.SetConfigType("yaml")
+	nodeprop.BindEnvironment()
 
 	// Read configuration
 	if err := viper.ReadInConfig(); err != nil {
@@ -112,49 +197,374 @@ func main() {
 	if err != nil {
 		logger.Fatalf("Failed to initialize NodePropManager: %v", err)
 	}
+	np.TemplateFallback = viper.GetBool("template_fallback")
+	np.WorkflowTemplateDir = viper.GetString("workflow_template_dir")
+	np.CatalogPath = viper.GetString("catalog_path")
+	np.AddressTemplate = viper.GetString("address_template")
+	np.AddressTemplates = viper.GetStringMapString("address_templates")
+	np.CapabilityKeywords = viper.GetStringMapStringSlice("capabilities.workflow_keywords")
+	np.Source, np.Profile = nodeprop.SourceCLI, *configPath
+	np.Workflows = nodeprop.WorkflowPolicy{
+		EnforcePermissions: viper.GetBool("workflows.enforce_permissions"),
+		EnforceConcurrency: viper.GetBool("workflows.enforce_concurrency"),
+		DefaultPermissions: viper.GetStringMapString("workflows.default_permissions"),
+		Normalize:          viper.GetBool("workflows.normalize"),
+	}
+	if np.IDs, err = nodeprop.NewIDGenerator(viper.GetString("id_generator")); err != nil {
+		logger.Fatalf("Failed to configure ID generation: %v", err)
+	}
+	np.Timeouts = nodeprop.Timeouts{
+		Default:    viper.GetDuration("timeouts.default"),
+		Operations: map[string]time.Duration{},
+	}
+	for _, operation := range nodeprop.Operations {
+		np.Timeouts.Operations[operation] = viper.GetDuration("timeouts." + operation)
+	}
+	if err := viper.UnmarshalKey("owners", &np.Owners); err != nil {
+		logger.Fatalf("Failed to read owner profiles: %v", err)
+	}
+	if *dryRun {
+		np.Files, np.DryRun = &nodeprop.RecordingFS{}, true
+	}
+	np.RequireSignature = viper.GetBool("signing.require_signature")
+	np.SignDetached = viper.GetBool("signing.detached")
+	if keyPath := viper.GetString("signing.private_key"); keyPath != "" {
+		if np.SigningKey, err = nodeprop.LoadSigningKey(keyPath); err != nil {
+			logger.Fatalf("Failed to load signing key: %v", err)
+		}
+	}
+
+	// Show, and with --confirm approve, each file change before it is written
+	if *showDiff {
+		np.ReviewChange = func(path, diff string) bool {
+			fmt.Print(diff)
+			if !*confirmChanges || *confirmed || !interactive() {
+				return true
+			}
+			return confirm(fmt.Sprintf("Write %s?", path))
+		}
+	}
 
-	// Subscribe to events (if any)
-	eventCh := np.SubscribeEvents()
+	// Subscribe to events before running any operation, so that none of their events is missed
+	eventTypes := map[nodeprop.EventType]bool{}
+	if *eventTypeList != "" {
+		for _, eventType := range strings.Split(*eventTypeList, ",") {
+			eventTypes[nodeprop.EventType(eventType)] = true
+		}
+	}
+	if *eventFormat != "" && *eventFormat != "jsonl" {
+		logger.Fatalf("Unknown events format '%s' (want jsonl)", *eventFormat)
+	}
+	eventCh, unsubscribe := np.Subscribe()
+	eventsDone := make(chan struct{})
 	go func() {
+		defer close(eventsDone)
+		encoder := json.NewEncoder(os.Stdout)
 		for event := range eventCh {
+			if len(eventTypes) > 0 && !eventTypes[event.Type] {
+				continue
+			}
+			if *eventFormat == "jsonl" {
+				line := struct {
+					Time string `json:"time"`
+					nodeprop.Event
+				}{time.Now().UTC().Format(time.RFC3339Nano), event}
+				if err := encoder.Encode(line); err != nil {
+					logger.Errorf("Failed to write event: %v", err)
+				}
+				continue
+			}
+			entry := logger.WithField(nodeprop.MetadataCorrelationID, event.Metadata[nodeprop.MetadataCorrelationID])
 			switch event.Type {
 			case nodeprop.EventTypeSuccess:
-				logger.Infof("SUCCESS: %s", event.Message)
+				entry.Infof("SUCCESS: %s", event.Message)
 			case nodeprop.EventTypeError:
-				logger.Errorf("ERROR: %s", event.Message)
+				entry.Errorf("ERROR: %s", event.Message)
 			case nodeprop.EventTypeInfo:
-				logger.Infof("INFO: %s", event.Message)
+				entry.Infof("INFO: %s", event.Message)
 			}
 		}
 	}()
 
+	// Unsubscribe and wait for the printer to drain the remaining events on every way out:
+	// returning from main, os.Exit below and logger.Fatalf, which runs the logrus exit handlers
+	var stopEventsOnce sync.Once
+	stopEvents := func() {
+		stopEventsOnce.Do(func() {
+			unsubscribe()
+			<-eventsDone
+		})
+	}
+	defer stopEvents()
+	logrus.RegisterExitHandler(stopEvents)
+
+	// Sign or verify a nodeprop file and exit
+	if *signPath != "" {
+		if np.SigningKey == nil {
+			logger.Fatalf("signing.private_key must be configured to sign %s", *signPath)
+		}
+		if err := np.SignFile(context.Background(), *signPath); err != nil {
+			logger.Fatalf("Failed to sign %s: %v", *signPath, err)
+		}
+		printPlan(np)
+		logger.Infof("Signed %s", *signPath)
+		return
+	}
+	if *verifyPath != "" {
+		trustedKeys, err := nodeprop.LoadTrustedKeys(viper.GetStringSlice("signing.trusted_keys"))
+		if err != nil {
+			logger.Fatalf("Failed to load trusted keys: %v", err)
+		}
+		if err := nodeprop.VerifyNodePropFile(*verifyPath, trustedKeys); err != nil {
+			logger.Fatalf("Verification of %s failed: %v", *verifyPath, err)
+		}
+		logger.Infof("Signature of %s is valid", *verifyPath)
+		return
+	}
+
+	// Print which run generated a nodeprop file and exit
+	if *provenancePath != "" {
+		documents, err := nodeprop.LoadNodePropFiles(*provenancePath)
+		if err != nil {
+			logger.Fatalf("Failed to load %s: %v", *provenancePath, err)
+		}
+		if len(documents) == 0 {
+			logger.Fatalf("%s contains no nodeprop document", *provenancePath)
+		}
+		output, err := yaml.Marshal(documents[0].Metadata.GeneratedBy)
+		if err != nil {
+			logger.Fatalf("Failed to encode provenance: %v", err)
+		}
+		fmt.Print(string(output))
+		return
+	}
+
+	// List workflow templates and exit
+	if *listTemplates {
+		templates, err := nodeprop.ListWorkflowTemplates(np.WorkflowTemplateDir)
+		if err != nil {
+			logger.Fatalf("Failed to list workflow templates: %v", err)
+		}
+		if outputTemplate != nil {
+			if err := nodeprop.RenderEach(os.Stdout, outputTemplate, templates); err != nil {
+				logger.Fatalf("Failed to render workflow templates: %v", err)
+			}
+			return
+		}
+		for _, template := range templates {
+			fmt.Printf("%-20s %-9s %s\n", template.Name, template.Source, template.Path)
+		}
+		return
+	}
+
+	// Print workflow badges and exit
+	if *badgeMarkdown {
+		repoURL := nodeprop.RepoAddress(*repoPath)
+		workflows, err := nodeprop.DiscoverWorkflows(*repoPath, *workflowDir, repoURL)
+		if err != nil {
+			logger.Warnf("Failed to parse some workflows: %v", err)
+		}
+		if outputTemplate != nil {
+			if err := nodeprop.RenderEach(os.Stdout, outputTemplate, workflows); err != nil {
+				logger.Fatalf("Failed to render workflows: %v", err)
+			}
+			return
+		}
+		for _, workflow := range workflows {
+			fmt.Println(workflow.BadgeMarkdown(repoURL))
+		}
+		return
+	}
+
+	// List workflows with their deprecation state and exit
+	if *listWorkflows {
+		workflows, err := nodeprop.DiscoverWorkflows(*repoPath, *workflowDir, nodeprop.RepoAddress(*repoPath))
+		if err != nil {
+			logger.Warnf("Failed to parse some workflows: %v", err)
+		}
+		if outputTemplate != nil {
+			if err := nodeprop.RenderEach(os.Stdout, outputTemplate, workflows); err != nil {
+				logger.Fatalf("Failed to render workflows: %v", err)
+			}
+			return
+		}
+		now := time.Now()
+		for _, workflow := range workflows {
+			state := workflow.State(now)
+			if workflow.Sunset != "" {
+				state += " (sunset " + workflow.Sunset + ")"
+			}
+			fmt.Printf("%-30s %-40s %s\n", workflow.Name, workflow.File, state)
+		}
+		return
+	}
+
+	// Deprecate a workflow and exit
+	if *deprecate {
+		sunsetDate, err := time.Parse("2006-01-02", *sunset)
+		if err != nil {
+			logger.Fatalf("--sunset must be a YYYY-MM-DD date: %v", err)
+		}
+		deprecation := nodeprop.NodePropArguments{RepoPath: *repoPath, Workflow: *workflowName, Path: *nodePropSubPath, Directory: *workflowDir}
+		if err := np.DeprecateWorkflow(context.Background(), deprecation, sunsetDate); err != nil {
+			logger.Fatalf("Failed to deprecate workflow: %v", err)
+		}
+		printPlan(np)
+		return
+	}
+
+	// Delete a nodeprop file and exit
+	if *deleteNodeProp {
+		target := filepath.Join(*repoPath, *nodePropSubPath)
+		if !*confirmed && !confirm(fmt.Sprintf("Delete the .nodeprop.yml in %s?", target)) {
+			logger.Fatalf("Refusing to delete the .nodeprop.yml in %s without --yes", target)
+		}
+		if err := np.DeleteNodeProp(context.Background(), target); err != nil {
+			if errors.Is(err, nodeprop.ErrNodePropNotFound) {
+				logger.Warn(err)
+				return
+			}
+			logger.Fatalf("Failed to delete .nodeprop.yml: %v", err)
+		}
+		printPlan(np)
+		return
+	}
+
+	// Load the nodeprop files under root, narrowed down by --exclude and --filter
+	loadFleet := func(root string) map[string]nodeprop.NodePropFile {
+		pattern := *discoveryGlob
+		if pattern == "" {
+			pattern = viper.GetString("discovery_glob")
+		}
+		fleet, err := nodeprop.LoadFleet(root, pattern)
+		if err != nil {
+			logger.Fatalf("Failed to load nodeprop files: %v", err)
+		}
+		var patterns []string
+		if *fleetExclude != "" {
+			patterns = strings.Split(*fleetExclude, ",")
+		}
+		fleet, excluded, err := nodeprop.ExcludeFromFleet(fleet, patterns, *includeArchived)
+		if err != nil {
+			logger.Fatalf("%v", err)
+		}
+		ids := make([]string, 0, len(excluded))
+		for id := range excluded {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			logger.Infof("Excluding %s: %s", id, excluded[id])
+		}
+		if *fleetFilter == "" {
+			return fleet
+		}
+		filter, err := nodeprop.ParseFilter(*fleetFilter)
+		if err != nil {
+			logger.Fatalf("%v", err)
+		}
+		fleet, err = nodeprop.FilterFleet(fleet, filter)
+		if err != nil {
+			logger.Fatalf("Failed to filter nodeprop files: %v", err)
+		}
+		return fleet
+	}
+
+	// Print the fleet dependency graph and exit
+	if *graphRoot != "" {
+		fleet := loadFleet(*graphRoot)
+		output, err := np.BuildDependencyGraph(fleet).Render(*graphFormat)
+		if err != nil {
+			logger.Fatalf("Failed to render dependency graph: %v", err)
+		}
+		fmt.Print(output)
+		return
+	}
+
+	// Run cross-service analyzers over the fleet and exit
+	if *analyze != "" {
+		fleet := loadFleet(*fleetRoot)
+		var overrides []nodeprop.Analyzer
+		if *checkDNS {
+			overrides = append(overrides, nodeprop.DomainsAnalyzer{
+				Resolver: net.DefaultResolver,
+				Expected: viper.GetStringMapString("domains.expected_targets"),
+				Timeout:  viper.GetDuration("domains.dns_timeout"),
+			})
+		}
+		var names []string
+		if *analyze != "all" {
+			names = strings.Split(*analyze, ",")
+		}
+		findings, err := nodeprop.RunAnalyzersWith(fleet, overrides, names...)
+		if err != nil {
+			logger.Fatalf("Failed to analyze fleet: %v", err)
+		}
+		if outputTemplate != nil {
+			if err := nodeprop.RenderEach(os.Stdout, outputTemplate, findings); err != nil {
+				logger.Fatalf("Failed to render findings: %v", err)
+			}
+		} else {
+			output, err := json.MarshalIndent(findings, "", "  ")
+			if err != nil {
+				logger.Fatalf("Failed to encode findings: %v", err)
+			}
+			fmt.Println(string(output))
+		}
+		for _, finding := range findings {
+			if finding.Severity == nodeprop.SeverityError {
+				stopEvents()
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
 	// Initialize the signal handler
 	signalHandler := NewSignalHandler()
 
 	// Listen for system signals (like SIGINT, SIGTERM) in a separate goroutine
 	go signalHandler.ListenForSignal()
 
+	// At most one source of workflow content may be given
+	contentSource := *fromFile
+	if *fromURL != "" {
+		contentSource = *fromURL
+	}
+	sources := 0
+	for _, source := range []string{*workflowTemplate, *fromFile, *fromURL} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		logger.Fatalf("--template, --from-file and --from-url are mutually exclusive")
+	}
+
 	// Define dynamic arguments for adding a workflow
 	args := nodeprop.NodePropArguments{
-		RepoPath: *repoPath,
-		Workflow: *workflowName,
+		RepoPath:      *repoPath,
+		Workflow:      *workflowName,
+		Domain:        *domain,
+		Path:          *nodePropSubPath,
+		RequireFile:   *requireFile,
+		SkipIfFile:    *skipIfFile,
+		Directory:     *workflowDir,
+		Template:      *workflowTemplate,
+		ContentSource: contentSource,
 	}
 
-	// Handle CLI args or signal-based actions dynamically using generics
+	// Handle CLI args or signal-based actions
 	go func() {
 		if *addWorkflow {
-			handleArgsOrSignals[np.AddWorkflow]("add_workflow", args, logger)
+			handleArgsOrSignals(np, "add_workflow", args, logger)
 		}
 
-		// Process actions from signals dynamically
+		// Process actions from signals
 		for action := range signalHandler.ActionCh {
-			// For actions like "shutdown" or "reload", use appropriate argument types
 			switch action {
-			case "shutdown":
-				var emptyArg struct{}
-				handleArgsOrSignals[np.Shutdown](action, emptyArg, logger)
-			case "reload":
-				handleArgsOrSignals[np.ReloadConfig]("reload", args, logger)
+			case "shutdown", "reload":
+				handleArgsOrSignals(np, action, args, logger)
 			default:
 				logger.Warnf("Unhandled action: %s", action)
 			}
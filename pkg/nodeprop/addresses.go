@@ -0,0 +1,83 @@
+// pkg/nodeprop/addresses.go
+package nodeprop
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// AddressContext is the data address templates are rendered with.
+type AddressContext struct {
+	Owner  string // GitHub owner of the repository, e.g. Cdaprod
+	Repo   string // repository name
+	Name   string // service name: the repository, or the service subdirectory in monorepos
+	Path   string // slash-separated service subdirectory; empty for a whole repository
+	Domain string // service domain, when known
+	GitHub string // default GitHub address of the service
+}
+
+// RenderAddress renders an address template, e.g. "https://registry.internal/{{.Owner}}/{{.Name}}",
+// and checks that the result is an absolute URL.
+func RenderAddress(text string, data AddressContext) (string, error) {
+	tmpl, err := template.New("address").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid address template '%s': %w", text, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render address template '%s': %w", text, err)
+	}
+
+	address := strings.TrimSpace(out.String())
+	u, err := url.Parse(address)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("address template '%s' does not give a valid URL for %s: '%s'", text, data.Name, address)
+	}
+	return address, nil
+}
+
+// serviceAddresses renders the service's address from AddressTemplate, or the GitHub address
+// when none is configured, and its named extra addresses from AddressTemplates.
+func (npm *NodePropManager) serviceAddresses(args NodePropArguments) (string, map[string]string, error) {
+	name, github := serviceIdentity(args)
+	data := AddressContext{
+		Owner:  RepoOwner(args.RepoPath),
+		Repo:   filepath.Base(args.RepoPath),
+		Name:   name,
+		Domain: args.Domain,
+		GitHub: github,
+	}
+	if args.Path != "" {
+		data.Path = filepath.ToSlash(filepath.Clean(args.Path))
+	}
+
+	address := github
+	if npm.AddressTemplate != "" {
+		var err error
+		if address, err = RenderAddress(npm.AddressTemplate, data); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if len(npm.AddressTemplates) == 0 {
+		return address, nil, nil
+	}
+	kinds := make([]string, 0, len(npm.AddressTemplates))
+	for kind := range npm.AddressTemplates {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	addresses := make(map[string]string, len(kinds))
+	for _, kind := range kinds {
+		rendered, err := RenderAddress(npm.AddressTemplates[kind], data)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s address: %w", kind, err)
+		}
+		addresses[kind] = rendered
+	}
+	return address, addresses, nil
+}
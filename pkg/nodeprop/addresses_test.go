@@ -0,0 +1,48 @@
+// pkg/nodeprop/addresses_test.go
+package nodeprop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceAddresses(t *testing.T) {
+	npManager := &NodePropManager{
+		AddressTemplate: "https://registry.internal/{{.Owner}}/{{.Name}}",
+		AddressTemplates: map[string]string{
+			"github":   "{{.GitHub}}",
+			"internal": "http://{{.Name}}.svc.cluster.local{{if .Path}}/{{.Path}}{{end}}",
+		},
+	}
+
+	address, addresses, err := npManager.serviceAddresses(NodePropArguments{RepoPath: "/src/platform", Path: "services/api"})
+	assert.NoError(t, err, "serviceAddresses failed")
+	assert.Equal(t, "https://registry.internal/Cdaprod/api", address, "The address should come from the template")
+	assert.Equal(t, map[string]string{
+		"github":   "https://github.com/Cdaprod/platform/tree/HEAD/services/api",
+		"internal": "http://api.svc.cluster.local/services/api",
+	}, addresses)
+
+	// Without templates the GitHub address is used
+	address, addresses, err = (&NodePropManager{}).serviceAddresses(NodePropArguments{RepoPath: "/src/api"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.com/Cdaprod/api", address)
+	assert.Empty(t, addresses)
+}
+
+func TestRenderAddressInvalid(t *testing.T) {
+	data := AddressContext{Owner: "Cdaprod", Repo: "api", Name: "api"}
+
+	_, err := RenderAddress("{{.Name}}.internal", data)
+	assert.ErrorContains(t, err, "valid URL", "Addresses without a scheme should be rejected")
+
+	_, err = RenderAddress("https://{{.Domain}}", data)
+	assert.ErrorContains(t, err, "valid URL", "Addresses without a host should be rejected")
+
+	_, err = RenderAddress("https://{{.Team}}.internal", data)
+	assert.Error(t, err, "Unknown fields should be rejected")
+
+	_, err = RenderAddress("https://{{.Name", data)
+	assert.ErrorContains(t, err, "invalid address template")
+}
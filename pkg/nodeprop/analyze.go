@@ -0,0 +1,246 @@
+// pkg/nodeprop/analyze.go
+package nodeprop
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Finding severities reported by analyzers.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// maxPortRange bounds how many ports of a published range are expanded.
+const maxPortRange = 1024
+
+// Finding is a structured result of a cross-service check.
+type Finding struct {
+	Analyzer string   `json:"analyzer"`
+	Severity string   `json:"severity"`
+	Resource string   `json:"resource"`
+	Message  string   `json:"message"`
+	Services []string `json:"services"`
+}
+
+// Analyzer is a cross-service check over a fleet of nodeprop files keyed by service.
+type Analyzer interface {
+	Name() string
+	Analyze(fleet map[string]NodePropFile) []Finding
+}
+
+// Analyzers lists the built-in analyzers by name.
+var Analyzers = map[string]Analyzer{
+	"domains": DomainsAnalyzer{},
+	"ports":   PortsAnalyzer{},
+}
+
+// RunAnalyzers runs the named analyzers (all of them when names is empty) over the fleet.
+func RunAnalyzers(fleet map[string]NodePropFile, names ...string) ([]Finding, error) {
+	return RunAnalyzersWith(fleet, nil, names...)
+}
+
+// RunAnalyzersWith is RunAnalyzers with overrides, such as a DomainsAnalyzer configured with a
+// resolver, used in place of the built-in analyzers of the same name. Analyzers is not modified.
+func RunAnalyzersWith(fleet map[string]NodePropFile, overrides []Analyzer, names ...string) ([]Finding, error) {
+	analyzers := make(map[string]Analyzer, len(Analyzers)+len(overrides))
+	for name, analyzer := range Analyzers {
+		analyzers[name] = analyzer
+	}
+	for _, analyzer := range overrides {
+		analyzers[analyzer.Name()] = analyzer
+	}
+	if len(names) == 0 {
+		for name := range analyzers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	findings := []Finding{}
+	for _, name := range names {
+		analyzer, ok := analyzers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown analyzer '%s'", name)
+		}
+		findings = append(findings, analyzer.Analyze(fleet)...)
+	}
+	return findings, nil
+}
+
+// sortedServices returns the fleet's keys in order so findings are stable.
+func sortedServices(fleet map[string]NodePropFile) []string {
+	services := make([]string, 0, len(fleet))
+	for service := range fleet {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// severityFor is an error when at least two of the services are active and a warning otherwise.
+func severityFor(fleet map[string]NodePropFile, services []string) string {
+	active := 0
+	for _, service := range services {
+		if fleet[service].Status == StatusActive {
+			active++
+		}
+	}
+	if active >= 2 {
+		return SeverityError
+	}
+	return SeverityWarning
+}
+
+// PortsAnalyzer flags services on the same network publishing the same host port, from
+// CustomProperties.Ports and the docker-compose port mappings.
+type PortsAnalyzer struct{}
+
+// Name implements Analyzer.
+func (PortsAnalyzer) Name() string {
+	return "ports"
+}
+
+// hostBinding is a host port published by a service.
+type hostBinding struct {
+	service string
+	ip      string // empty for all interfaces
+}
+
+// Analyze implements Analyzer.
+func (a PortsAnalyzer) Analyze(fleet map[string]NodePropFile) []Finding {
+	// network/protocol/port -> bindings
+	bindings := map[string][]hostBinding{}
+	var keys []string
+	bind := func(service, network, spec string, bareIsHost bool) {
+		ip, ports, protocol, ok := parseHostPorts(spec, bareIsHost)
+		if !ok {
+			return
+		}
+		for _, port := range ports {
+			key := fmt.Sprintf("%s %s/%d", network, protocol, port)
+			if _, seen := bindings[key]; !seen {
+				keys = append(keys, key)
+			}
+			bindings[key] = append(bindings[key], hostBinding{service: service, ip: ip})
+		}
+	}
+
+	for _, service := range sortedServices(fleet) {
+		nodeProp := fleet[service]
+		network := nodeProp.CustomProperties.Network
+		if network == "" {
+			network = "default"
+		}
+
+		for _, spec := range nodeProp.CustomProperties.Ports {
+			bind(service, network, spec, true)
+		}
+		compose := nodeProp.Metadata.Docker.DockerCompose
+		for _, composeService := range compose.Services {
+			for _, spec := range composeService.Ports {
+				bind(service, network, spec, false)
+			}
+		}
+		for _, ports := range compose.Ports {
+			for _, port := range ports {
+				bind(service, network, strconv.Itoa(port), true)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	var findings []Finding
+	for _, key := range keys {
+		services := collidingServices(bindings[key])
+		if len(services) < 2 {
+			continue
+		}
+		network := key[:strings.LastIndex(key, " ")]
+		port := key[strings.LastIndex(key, " ")+1:]
+		findings = append(findings, Finding{
+			Analyzer: a.Name(),
+			Severity: severityFor(fleet, services),
+			Resource: fmt.Sprintf("%s on network %s", port, network),
+			Message:  fmt.Sprintf("host port %s is published by %d services on network '%s': %s", port, len(services), network, strings.Join(services, ", ")),
+			Services: services,
+		})
+	}
+	return findings
+}
+
+// collidingServices returns the distinct services whose bindings overlap: the same host IP,
+// or either binding listening on all interfaces.
+func collidingServices(bindings []hostBinding) []string {
+	colliding := map[string]bool{}
+	for i, a := range bindings {
+		for _, b := range bindings[i+1:] {
+			if a.service != b.service && (a.ip == "" || b.ip == "" || a.ip == b.ip) {
+				colliding[a.service] = true
+				colliding[b.service] = true
+			}
+		}
+	}
+
+	services := make([]string, 0, len(colliding))
+	for service := range colliding {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// parseHostPorts parses a docker-style port spec such as "8080", "8080:80", "127.0.0.1:8080:80"
+// or "8000-8002:8000-8002/udp" into the host IP, host ports and protocol. A bare port is a
+// container-only port (no host binding) unless bareIsHost is set.
+func parseHostPorts(spec string, bareIsHost bool) (string, []int, string, bool) {
+	protocol := "tcp"
+	if i := strings.LastIndex(spec, "/"); i >= 0 {
+		protocol = spec[i+1:]
+		spec = spec[:i]
+	}
+
+	parts := strings.Split(strings.TrimSpace(spec), ":")
+	var ip, host string
+	switch len(parts) {
+	case 1:
+		if !bareIsHost {
+			return "", nil, "", false
+		}
+		host = parts[0]
+	case 2:
+		host = parts[0]
+	case 3:
+		ip, host = parts[0], parts[1]
+	default:
+		return "", nil, "", false
+	}
+	if ip == "0.0.0.0" {
+		ip = ""
+	}
+	if host == "" {
+		return "", nil, "", false
+	}
+
+	start, end := host, host
+	if i := strings.Index(host, "-"); i >= 0 {
+		start, end = host[:i], host[i+1:]
+	}
+	first, err := strconv.Atoi(start)
+	if err != nil {
+		return "", nil, "", false
+	}
+	last, err := strconv.Atoi(end)
+	if err != nil || last < first || last-first >= maxPortRange {
+		return "", nil, "", false
+	}
+
+	ports := make([]int, 0, last-first+1)
+	for port := first; port <= last; port++ {
+		ports = append(ports, port)
+	}
+	return ip, ports, protocol, true
+}
@@ -0,0 +1,108 @@
+// pkg/nodeprop/analyze_test.go
+package nodeprop
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortsAnalyzer(t *testing.T) {
+	withComposePorts := func(network string, ports ...string) NodePropFile {
+		nodeProp := NodePropFile{Status: "active", CustomProperties: CustomProperties{Network: network}}
+		nodeProp.Metadata.Docker.DockerCompose.Services = []Service{{Name: "app", Ports: ports}}
+		return nodeProp
+	}
+
+	fleet := map[string]NodePropFile{
+		// Both active on backend: error
+		"api":     withComposePorts("backend", "8080:80"),
+		"sidecar": {Status: "active", CustomProperties: CustomProperties{Network: "backend", Ports: []string{"8080"}}},
+		// Only one active, loopback vs all interfaces still collide: warning
+		"admin":   {Status: "inactive", CustomProperties: CustomProperties{Network: "backend", Ports: []string{"127.0.0.1:9090:90"}}},
+		"metrics": {Status: "active", CustomProperties: CustomProperties{Network: "backend", Ports: []string{"9090"}}},
+		// Different networks, protocols and host IPs, or container-only ports: no collision
+		"web":    {Status: "active", CustomProperties: CustomProperties{Network: "frontend", Ports: []string{"8080"}}},
+		"worker": withComposePorts("", "8080:80", "9090"),
+		"legacy": withComposePorts("", "8080:8080/udp"),
+		"local":  withComposePorts("frontend", "127.0.0.1:8443:443"),
+		"remote": withComposePorts("frontend", "10.0.0.5:8443:443"),
+	}
+
+	findings, err := RunAnalyzers(fleet, "ports")
+	assert.NoError(t, err, "RunAnalyzers failed")
+	assert.Equal(t, []Finding{
+		{
+			Analyzer: "ports",
+			Severity: SeverityError,
+			Resource: "tcp/8080 on network backend",
+			Message:  "host port tcp/8080 is published by 2 services on network 'backend': api, sidecar",
+			Services: []string{"api", "sidecar"},
+		},
+		{
+			Analyzer: "ports",
+			Severity: SeverityWarning,
+			Resource: "tcp/9090 on network backend",
+			Message:  "host port tcp/9090 is published by 2 services on network 'backend': admin, metrics",
+			Services: []string{"admin", "metrics"},
+		},
+	}, findings, "Port collision findings mismatch")
+}
+
+func TestParseHostPorts(t *testing.T) {
+	tests := []struct {
+		spec       string
+		bareIsHost bool
+		ip         string
+		ports      []int
+		protocol   string
+		ok         bool
+	}{
+		{spec: "8080", bareIsHost: true, ports: []int{8080}, protocol: "tcp", ok: true},
+		{spec: "8080", bareIsHost: false},
+		{spec: "8080:80", ports: []int{8080}, protocol: "tcp", ok: true},
+		{spec: "0.0.0.0:8080:80", ports: []int{8080}, protocol: "tcp", ok: true},
+		{spec: "127.0.0.1:8080:80/udp", ip: "127.0.0.1", ports: []int{8080}, protocol: "udp", ok: true},
+		{spec: "8000-8002:8000-8002", ports: []int{8000, 8001, 8002}, protocol: "tcp", ok: true},
+		{spec: ":80"},
+		{spec: "http:80"},
+	}
+
+	for _, tt := range tests {
+		ip, ports, protocol, ok := parseHostPorts(tt.spec, tt.bareIsHost)
+		assert.Equal(t, tt.ok, ok, "Unexpected result parsing %s", tt.spec)
+		if tt.ok {
+			assert.Equal(t, tt.ip, ip, "Host IP mismatch for %s", tt.spec)
+			assert.Equal(t, tt.ports, ports, "Host ports mismatch for %s", tt.spec)
+			assert.Equal(t, tt.protocol, protocol, "Protocol mismatch for %s", tt.spec)
+		}
+	}
+}
+
+func TestRunAnalyzersUnknown(t *testing.T) {
+	_, err := RunAnalyzers(map[string]NodePropFile{}, "nope")
+	assert.Error(t, err, "Expected an error for an unknown analyzer")
+}
+
+func BenchmarkRunAnalyzers(b *testing.B) {
+	fleet := make(map[string]NodePropFile, 100)
+	for i := 0; i < 100; i++ {
+		fleet[fmt.Sprintf("svc%03d", i)] = NodePropFile{
+			Status: "active",
+			CustomProperties: CustomProperties{
+				Network: fmt.Sprintf("net%d", i%4),
+				Ports:   []string{fmt.Sprint(8000 + i%50), "9000-9010"},
+				Domain:  fmt.Sprintf("svc%03d.cdaprod.dev", i%90),
+			},
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := RunAnalyzers(fleet); err != nil {
+			b.Fatalf("RunAnalyzers failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// pkg/nodeprop/capabilities.go
+package nodeprop
+
+import (
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultCapabilityKeywords maps capabilities to the keywords that imply them when found in one
+// of the repository's workflows, matched case-insensitively.
+var DefaultCapabilityKeywords = map[string][]string{
+	"docker": {
+		"docker build",
+		"docker push",
+		"docker/build-push-action",
+		"docker/setup-buildx-action",
+	},
+	"deployable": {
+		"kubectl apply",
+		"helm install",
+		"helm upgrade",
+		"terraform apply",
+		"flyctl deploy",
+		"actions/deploy-pages",
+		"aws-actions/amazon-ecs-deploy-task-definition",
+		"azure/webapps-deploy",
+	},
+	"releasable": {
+		"goreleaser",
+		"softprops/action-gh-release",
+		"semantic-release",
+	},
+}
+
+// capabilityKeywords returns the default keywords overridden per capability by the configured
+// ones; a capability configured with no keywords is not inferred.
+func (npm *NodePropManager) capabilityKeywords() map[string][]string {
+	keywords := make(map[string][]string, len(DefaultCapabilityKeywords)+len(npm.CapabilityKeywords))
+	for capability, words := range DefaultCapabilityKeywords {
+		keywords[capability] = words
+	}
+	for capability, words := range npm.CapabilityKeywords {
+		keywords[capability] = words
+	}
+	return keywords
+}
+
+// InferCapabilities returns, sorted, the capabilities whose keywords appear in any workflow in
+// workflowDir, a directory of the repository (`.github/workflows` when empty).
+func InferCapabilities(repoPath, workflowDir string, keywords map[string][]string) ([]string, error) {
+	return inferCapabilities(os.DirFS(repoPath), workflowDir, keywords)
+}
+
+// inferCapabilities implements InferCapabilities over the repository filesystem repo.
+func inferCapabilities(repo fs.FS, workflowDir string, keywords map[string][]string) ([]string, error) {
+	files, err := readWorkflowDir(repo, workflowDir)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	for _, file := range files {
+		content, err := fs.ReadFile(repo, file)
+		if err != nil {
+			return nil, err
+		}
+		text := strings.ToLower(string(content))
+		for capability, words := range keywords {
+			for _, word := range words {
+				if word != "" && strings.Contains(text, strings.ToLower(word)) {
+					found[capability] = true
+					break
+				}
+			}
+		}
+	}
+
+	capabilities := make([]string, 0, len(found))
+	for capability := range found {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+	return capabilities, nil
+}
+
+// mergeCapabilities appends the capabilities missing from existing, keeping its order.
+func mergeCapabilities(existing, inferred []string) []string {
+	seen := make(map[string]bool, len(existing))
+	for _, capability := range existing {
+		seen[capability] = true
+	}
+	for _, capability := range inferred {
+		if !seen[capability] {
+			existing = append(existing, capability)
+			seen[capability] = true
+		}
+	}
+	return existing
+}
@@ -0,0 +1,58 @@
+// pkg/nodeprop/capabilities_test.go
+package nodeprop
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferCapabilities(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	dir := filepath.Join(repoPath, ".github", "workflows")
+	assert.NoError(t, os.MkdirAll(dir, 0755))
+	workflow := `
+name: Publish
+on: push
+jobs:
+  image:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: Docker/Build-Push-Action@v5
+        with:
+          push: true
+`
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "publish.yml"), []byte(workflow), 0644))
+
+	capabilities, err := InferCapabilities(repoPath, "", DefaultCapabilityKeywords)
+	assert.NoError(t, err, "InferCapabilities failed")
+	assert.Equal(t, []string{"docker"}, capabilities, "docker/build-push-action should imply docker")
+
+	// Configured keywords override the defaults per capability
+	npManager := &NodePropManager{CapabilityKeywords: map[string][]string{
+		"docker":     {},
+		"publishing": {"push: true"},
+	}}
+	capabilities, err = InferCapabilities(repoPath, "", npManager.capabilityKeywords())
+	assert.NoError(t, err, "InferCapabilities failed")
+	assert.Equal(t, []string{"publishing"}, capabilities, "Configured keywords should replace the defaults")
+}
+
+func TestInferCapabilitiesWithoutWorkflows(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	capabilities, err := InferCapabilities(repoPath, "", DefaultCapabilityKeywords)
+	assert.NoError(t, err, "A repository without workflows should not fail")
+	assert.Empty(t, capabilities)
+}
+
+func TestMergeCapabilities(t *testing.T) {
+	merged := mergeCapabilities([]string{"http", "docker"}, []string{"deployable", "docker"})
+	assert.Equal(t, []string{"http", "docker", "deployable"}, merged, "Capabilities should not be duplicated")
+}
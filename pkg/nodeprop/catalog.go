@@ -0,0 +1,236 @@
+// pkg/nodeprop/catalog.go
+package nodeprop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v2"
+)
+
+// Catalog lock timing. A lock file older than catalogStaleLock is left behind by a crashed
+// writer and is broken.
+const (
+	catalogLockRetry = 10 * time.Millisecond
+	catalogStaleLock = time.Minute
+)
+
+// CatalogEntry summarizes one nodeprop file in the catalog.
+type CatalogEntry struct {
+	ID           string   `yaml:"id"`
+	Name         string   `yaml:"name"`
+	Address      string   `yaml:"address"` // identifies the entry
+	Status       string   `yaml:"status"`
+	Domain       string   `yaml:"domain,omitempty"`
+	Capabilities []string `yaml:"capabilities,omitempty"`
+}
+
+// Catalog is the aggregate YAML file listing every nodeprop file generated by the manager,
+// kept sorted by name (then address) for stable diffs.
+type Catalog struct {
+	Nodes []CatalogEntry `yaml:"nodes"`
+}
+
+// NewCatalogEntry summarizes a nodeprop file.
+func NewCatalogEntry(nodeProp NodePropFile) CatalogEntry {
+	return CatalogEntry{
+		ID:           nodeProp.ID,
+		Name:         nodeProp.Name,
+		Address:      nodeProp.Address,
+		Status:       nodeProp.Status,
+		Domain:       nodeProp.CustomProperties.Domain,
+		Capabilities: nodeProp.Capabilities,
+	}
+}
+
+// LoadCatalog reads the catalog at path. A missing file is an empty catalog.
+func LoadCatalog(path string) (Catalog, error) {
+	return loadCatalog(ioutil.ReadFile, path)
+}
+
+func loadCatalog(readFile func(string) ([]byte, error), path string) (Catalog, error) {
+	var catalog Catalog
+	data, err := readFile(path)
+	if os.IsNotExist(err) {
+		return catalog, nil
+	}
+	if err != nil {
+		return catalog, err
+	}
+	if err := yaml.Unmarshal(data, &catalog); err != nil {
+		return catalog, fmt.Errorf("failed to parse catalog %s: %w", path, err)
+	}
+	return catalog, nil
+}
+
+// UpdateCatalog applies update to the catalog at path while holding its lock file, then
+// rewrites it atomically. Concurrent updates, from goroutines or other processes, are
+// serialized and never lose each other's entries.
+func UpdateCatalog(ctx context.Context, path string, update func(*Catalog) error) error {
+	return updateCatalogFile(ctx, OSFS{}, ioutil.ReadFile, path, update)
+}
+
+// updateCatalogFile implements UpdateCatalog, reading the catalog with readFile and writing it
+// through files. The lock file is only taken when files is the local filesystem.
+func updateCatalogFile(ctx context.Context, files WriteFS, readFile func(string) ([]byte, error), path string, update func(*Catalog) error) error {
+	if _, local := files.(OSFS); local {
+		unlock, err := lockCatalog(ctx, path)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	catalog, err := loadCatalog(readFile, path)
+	if err != nil {
+		return err
+	}
+	if err := update(&catalog); err != nil {
+		return err
+	}
+	sort.Slice(catalog.Nodes, func(i, j int) bool {
+		a, b := catalog.Nodes[i], catalog.Nodes[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Address < b.Address
+	})
+
+	data, err := yaml.Marshal(&catalog)
+	if err != nil {
+		return err
+	}
+	if err := files.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return files.WriteFile(path, data, 0644)
+}
+
+// UpsertCatalogEntry adds the entry to the catalog at path, replacing any entry with the same address.
+func UpsertCatalogEntry(ctx context.Context, path string, entry CatalogEntry) error {
+	return UpdateCatalog(ctx, path, upsertCatalogEntry(entry))
+}
+
+func upsertCatalogEntry(entry CatalogEntry) func(*Catalog) error {
+	return func(catalog *Catalog) error {
+		for i := range catalog.Nodes {
+			if catalog.Nodes[i].Address == entry.Address {
+				catalog.Nodes[i] = entry
+				return nil
+			}
+		}
+		catalog.Nodes = append(catalog.Nodes, entry)
+		return nil
+	}
+}
+
+// RemoveCatalogEntry removes the entry with the address from the catalog at path, if present.
+func RemoveCatalogEntry(ctx context.Context, path, address string) error {
+	return UpdateCatalog(ctx, path, removeCatalogEntry(address))
+}
+
+func removeCatalogEntry(address string) func(*Catalog) error {
+	return func(catalog *Catalog) error {
+		nodes := catalog.Nodes[:0]
+		for _, entry := range catalog.Nodes {
+			if entry.Address != address {
+				nodes = append(nodes, entry)
+			}
+		}
+		catalog.Nodes = nodes
+		return nil
+	}
+}
+
+// lockCatalog takes the lock file next to the catalog, waiting until it is free or ctx is done.
+func lockCatalog(ctx context.Context, path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	lockPath := path + ".lock"
+	for {
+		lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			lock.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to lock catalog %s: %w", path, err)
+		}
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > catalogStaleLock {
+			breakStaleLock(lockPath, info)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for the lock on catalog %s: %w", path, ctx.Err())
+		case <-time.After(catalogLockRetry):
+		}
+	}
+}
+
+// breakStaleLock breaks the lock file at lockPath if it is still the stale file described by
+// stale. The lock is first renamed to a name of its own, so of several waiters that saw it
+// stale only one breaks it, and a waiter that loses the race with a new holder puts the new
+// lock back instead of deleting it.
+func breakStaleLock(lockPath string, stale os.FileInfo) {
+	broken := lockPath + ".stale-" + uuid.NewString()
+	if err := os.Rename(lockPath, broken); err != nil {
+		return // broken or released by someone else meanwhile
+	}
+	defer os.Remove(broken)
+	if info, err := os.Stat(broken); err == nil && !os.SameFile(info, stale) {
+		os.Link(broken, lockPath)
+	}
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and renames it over
+// path, so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// updateCatalog adds nodeProp to the configured catalog, or removes it when remove is set,
+// through the manager's filesystem. Failures are logged rather than failing the operation that
+// wrote the nodeprop file.
+func (npm *NodePropManager) updateCatalog(ctx context.Context, nodeProp NodePropFile, remove bool) {
+	if npm.CatalogPath == "" {
+		return
+	}
+	if npm.DryRun {
+		npm.logger(ctx).Debugf("Not updating the catalog %s in a dry run", npm.CatalogPath)
+		return
+	}
+	update := upsertCatalogEntry(NewCatalogEntry(nodeProp))
+	if remove {
+		update = removeCatalogEntry(nodeProp.Address)
+	}
+	if err := updateCatalogFile(ctx, npm.files(), npm.readFile, npm.CatalogPath, update); err != nil {
+		npm.logger(ctx).Warnf("Failed to update catalog %s: %v", npm.CatalogPath, err)
+	}
+}
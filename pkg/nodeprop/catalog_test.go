@@ -0,0 +1,105 @@
+// pkg/nodeprop/catalog_test.go
+package nodeprop
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogUpsertAndRemove(t *testing.T) {
+	tempDir := setupTempRepo(t)
+	defer teardownTempRepo(t, tempDir)
+	path := filepath.Join(tempDir, "catalog", "nodeprop-catalog.yml")
+	ctx := context.Background()
+
+	assert.NoError(t, UpsertCatalogEntry(ctx, path, CatalogEntry{Name: "web", Address: "https://github.com/Cdaprod/web"}))
+	assert.NoError(t, UpsertCatalogEntry(ctx, path, CatalogEntry{Name: "api", Address: "https://github.com/Cdaprod/api"}))
+	assert.NoError(t, UpsertCatalogEntry(ctx, path, CatalogEntry{Name: "api", Address: "https://github.com/Cdaprod/api", Status: "active"}))
+
+	catalog, err := LoadCatalog(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []CatalogEntry{
+		{Name: "api", Address: "https://github.com/Cdaprod/api", Status: "active"},
+		{Name: "web", Address: "https://github.com/Cdaprod/web"},
+	}, catalog.Nodes, "Entries should be updated in place and sorted by name")
+
+	assert.NoError(t, RemoveCatalogEntry(ctx, path, "https://github.com/Cdaprod/web"))
+	catalog, err = LoadCatalog(path)
+	assert.NoError(t, err)
+	assert.Len(t, catalog.Nodes, 1)
+	assert.NoFileExists(t, path+".lock", "The lock should be released")
+}
+
+func TestCatalogConcurrentUpdates(t *testing.T) {
+	tempDir := setupTempRepo(t)
+	defer teardownTempRepo(t, tempDir)
+	path := filepath.Join(tempDir, "nodeprop-catalog.yml")
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 10; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				name := fmt.Sprintf("service-%d-%d", worker, i)
+				err := UpsertCatalogEntry(context.Background(), path, CatalogEntry{Name: name, Address: "https://github.com/Cdaprod/" + name})
+				assert.NoError(t, err)
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	catalog, err := LoadCatalog(path)
+	assert.NoError(t, err)
+	assert.Len(t, catalog.Nodes, 100, "No concurrent update should be lost")
+}
+
+func TestCatalogLockTimeout(t *testing.T) {
+	tempDir := setupTempRepo(t)
+	defer teardownTempRepo(t, tempDir)
+	path := filepath.Join(tempDir, "nodeprop-catalog.yml")
+
+	unlock, err := lockCatalog(context.Background(), path)
+	assert.NoError(t, err)
+	defer unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = UpsertCatalogEntry(ctx, path, CatalogEntry{Name: "api"})
+	assert.ErrorIs(t, err, context.Canceled, "A held lock should not be taken")
+}
+
+func TestCatalogBreaksStaleLock(t *testing.T) {
+	tempDir := setupTempRepo(t)
+	defer teardownTempRepo(t, tempDir)
+	path := filepath.Join(tempDir, "nodeprop-catalog.yml")
+
+	// A lock left behind by a crashed writer is broken
+	lockPath := path + ".lock"
+	assert.NoError(t, ioutil.WriteFile(lockPath, nil, 0644))
+	old := time.Now().Add(-2 * catalogStaleLock)
+	assert.NoError(t, os.Chtimes(lockPath, old, old))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, UpsertCatalogEntry(ctx, path, CatalogEntry{Name: "api", Address: "https://github.com/Cdaprod/api"}))
+	entries, err := ioutil.ReadDir(tempDir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "Only the catalog should be left")
+
+	// A lock taken by a new holder after it was seen stale is put back
+	assert.NoError(t, ioutil.WriteFile(lockPath, nil, 0644))
+	assert.NoError(t, ioutil.WriteFile(lockPath+".old", nil, 0644))
+	seen, err := os.Stat(lockPath + ".old")
+	assert.NoError(t, err)
+	breakStaleLock(lockPath, seen)
+	assert.FileExists(t, lockPath, "A live lock should not be broken")
+}
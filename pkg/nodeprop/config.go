@@ -2,16 +2,49 @@
 package nodeprop
 
 import (
+	"crypto/ed25519"
 	"fmt"
+	"strings"
+	"sync"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
+// EnvPrefix prefixes the environment variables that override config keys.
+const EnvPrefix = "NODEPROP"
+
 // NodePropManager handles adding workflows and managing .nodeprop.yml files
 type NodePropManager struct {
 	GlobalNodePropPath 		string
 	WorkflowTemplatePath 	string
+	WorkflowTemplateDir		string // User workflow templates selectable by name, overriding the embedded ones
+	TemplateFallback   		bool // Fall back to the embedded .empty.nodeprop.yml when the on-disk template is broken
+	Workflows          		WorkflowPolicy
+	SigningKey         		ed25519.PrivateKey // Signs generated .nodeprop.yml files when set
+	SignDetached       		bool               // Store signatures in .nodeprop.yml.sig instead of inline
+	RequireSignature   		bool               // Refuse to write unsigned .nodeprop.yml files
+	IDs                		IDGenerator        // Generates nodeprop IDs; random UUIDs when nil
+	Timeouts           		Timeouts           // Per-operation timeouts
+	Owners             		map[string]OwnerProfile // Defaults for repositories of each GitHub owner
+	CatalogPath        		string             // Aggregate catalog of generated nodeprop files, updated when set
+	AddressTemplate    		string             // Template of the service address; the GitHub URL when empty
+	AddressTemplates   		map[string]string  // Templates of extra service addresses by kind
+	CapabilityKeywords 		map[string][]string // Workflow keywords implying each capability, overriding DefaultCapabilityKeywords
+	Source             		string             // Invocation source recorded in generated_by; SourceAPI when empty
+	Profile            		string             // Configuration file recorded in generated_by
+	ReviewChange       		func(path, diff string) bool // Shown the diff of every file before it is written; returning false aborts
+	Middleware         		[]OperationMiddleware // Wraps every public operation, outermost first
+	Files              		WriteFS            // Receives the files written and removed, and is read from when it implements io/fs.FS; the local filesystem when nil
+	DryRun             		bool               // Change nothing outside Files, e.g. a RecordingFS; changes are discarded when Files is nil
 	Logger             		*logrus.Logger
+
+	subscribersMu      		sync.RWMutex
+	subscribers        		map[int]chan Event // Subscribe channels by subscription ID
+	nextSubscriberID   		int
+
+	repoLocksMu        		sync.Mutex
+	repoLocks          		map[string]*repoLock // Per-repository operation locks by owner/repo
 }
 
 // NewNodePropManager initializes the NodePropManager with paths from the config
@@ -30,13 +63,11 @@ func NewNodePropManager(globalNodePropPath, workflowTemplatePath string, logger
 	}, nil
 }
 
-// ReloadConfig reloads the Viper configuration
-func (npm *NodePropManager) ReloadConfig(arg NodePropArguments) error {
-	err := viper.ReadInConfig()
-	if err != nil {
-		npm.Logger.Errorf("Error reading config file: %v", err)
-		return err
-	}
-	npm.Logger.Info("Configuration reloaded successfully")
-	return nil
-}
\ No newline at end of file
+// BindEnvironment lets every config key be overridden by an environment variable named after
+// it, e.g. NODEPROP_WORKFLOWS_ENFORCE_PERMISSIONS for workflows.enforce_permissions.
+// Environment variables take precedence over the config file.
+func BindEnvironment() {
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+}
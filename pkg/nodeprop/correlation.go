@@ -0,0 +1,53 @@
+// pkg/nodeprop/correlation.go
+package nodeprop
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MetadataCorrelationID is the Event.Metadata key and log field holding the correlation ID of
+// the operation an event or log line belongs to.
+const MetadataCorrelationID = "correlation_id"
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context carrying id as the correlation ID of the operations run
+// with it. Operations run without one are given a fresh ID.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, or "" when it carries none.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// withCorrelationID makes sure ctx carries a correlation ID, generating one with the manager's
+// ID generator when it does not.
+func (npm *NodePropManager) withCorrelationID(ctx context.Context) context.Context {
+	if CorrelationID(ctx) != "" {
+		return ctx
+	}
+	return WithCorrelationID(ctx, npm.newID())
+}
+
+// logger returns the manager's logger with the correlation ID of ctx attached.
+func (npm *NodePropManager) logger(ctx context.Context) *logrus.Entry {
+	return npm.Logger.WithField(MetadataCorrelationID, CorrelationID(ctx))
+}
+
+// emit publishes event tagged with the correlation ID of ctx.
+func (npm *NodePropManager) emit(ctx context.Context, event Event) {
+	if id := CorrelationID(ctx); id != "" {
+		metadata := make(map[string]string, len(event.Metadata)+1)
+		for key, value := range event.Metadata {
+			metadata[key] = value
+		}
+		metadata[MetadataCorrelationID] = id
+		event.Metadata = metadata
+	}
+	npm.Emit(event)
+}
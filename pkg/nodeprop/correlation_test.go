@@ -0,0 +1,53 @@
+// pkg/nodeprop/correlation_test.go
+package nodeprop
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorrelationIDPropagatesToEvents(t *testing.T) {
+	memFS, repoPath := setupMemRepo(t)
+
+	var observed []string
+	npManager := (&NodePropManager{
+		GlobalNodePropPath: filepath.Join("..", "..", "assets", ".empty.nodeprop.yml"),
+		Files:              memFS,
+		Logger:             logrus.New(),
+	}).WithOperationMiddleware(func(next OpFunc) OpFunc {
+		return func(ctx context.Context, op Operation) error {
+			observed = append(observed, CorrelationID(ctx))
+			return next(ctx, op)
+		}
+	})
+	events, unsubscribe := npManager.Subscribe()
+	defer unsubscribe()
+
+	// An operation run without a correlation ID is given one
+	_, err := npManager.AddWorkflowWithResult(NodePropArguments{RepoPath: repoPath, Workflow: "ci", Template: "go-ci"})
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+	event := <-events
+	assert.Equal(t, EventTypeSuccess, event.Type)
+	assert.NotEmpty(t, event.Metadata[MetadataCorrelationID], "Events should carry a generated correlation ID")
+	assert.Equal(t, observed[0], event.Metadata[MetadataCorrelationID], "Middleware and events should see the same correlation ID")
+
+	// A correlation ID given by the caller is kept
+	ctx := WithCorrelationID(context.Background(), "op-42")
+	assert.NoError(t, npManager.DeprecateWorkflow(ctx, NodePropArguments{RepoPath: repoPath, Workflow: "ci"}, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	event = <-events
+	assert.Equal(t, "op-42", event.Metadata[MetadataCorrelationID], "Events should carry the caller's correlation ID")
+	assert.Equal(t, "op-42", observed[1])
+
+	// Failure events carry the correlation ID of their own operation
+	_, err = npManager.AddWorkflowWithResult(NodePropArguments{RepoPath: repoPath, Workflow: "ci", Template: "missing"})
+	assert.Error(t, err)
+	event = <-events
+	assert.Equal(t, EventTypeError, event.Type)
+	assert.Equal(t, observed[2], event.Metadata[MetadataCorrelationID])
+	assert.NotEqual(t, observed[0], observed[2], "Every operation should get its own correlation ID")
+}
@@ -0,0 +1,184 @@
+// pkg/nodeprop/diff.go
+package nodeprop
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change in a unified diff.
+const diffContext = 3
+
+// maxDiffCells bounds the table diffLines needs for the lines between the common prefix and
+// suffix of two files, keeping its memory use to 16 MiB; larger changes are only summarized.
+const maxDiffCells = 4 << 20
+
+// ErrChangeDeclined is returned when ReviewChange declines a change; nothing further is written.
+var ErrChangeDeclined = errors.New("change declined")
+
+// reviewChange shows the change of the file at path from old to new to ReviewChange, if set,
+// and returns ErrChangeDeclined when it is declined. Unchanged files are not shown.
+func (npm *NodePropManager) reviewChange(repoPath, path string, old, new []byte) error {
+	if npm.ReviewChange == nil || string(old) == string(new) {
+		return nil
+	}
+	name := path
+	if rel, err := filepath.Rel(repoPath, path); err == nil {
+		name = filepath.ToSlash(rel)
+	}
+	if !npm.ReviewChange(path, UnifiedDiff(name, old, new)) {
+		return fmt.Errorf("%s: %w", path, ErrChangeDeclined)
+	}
+	return nil
+}
+
+// UnifiedDiff returns the line-based unified diff from old to new of the file at path, or an
+// empty string when they are equal. A missing file is passed as nil old content. Changes too
+// large to diff are summarized instead of shown line by line.
+func UnifiedDiff(path string, old, new []byte) string {
+	a, b := splitLines(old), splitLines(new)
+	ops, ok := diffLines(a, b)
+	if ok && len(ops) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- a/%s\n+++ b/%s\n", path, path)
+	if !ok {
+		fmt.Fprintf(&out, "Files differ (%d lines before, %d after); the change is too large to show\n", len(a), len(b))
+		return out.String()
+	}
+
+	// Group the edit script into hunks of changes separated by more than 2*diffContext
+	// unchanged lines.
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		from := start - diffContext
+		if from < 0 {
+			from = 0
+		}
+		end, unchanged := start, 0
+		for i := start; i < len(ops) && unchanged <= 2*diffContext; i++ {
+			if ops[i].kind == ' ' {
+				unchanged++
+			} else {
+				end, unchanged = i, 0
+			}
+		}
+		to := end + 1 + diffContext
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		hunk := ops[from:to]
+		oldStart, newStart := hunk[0].oldLine, hunk[0].newLine
+		var oldCount, newCount int
+		for _, op := range hunk {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, op := range hunk {
+			fmt.Fprintf(&out, "%c%s\n", op.kind, op.text)
+		}
+		start = to
+	}
+	return out.String()
+}
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed or '+' added. oldLine and newLine
+// are the 1-based positions of the line, or of the next line, in each file.
+type diffOp struct {
+	kind             byte
+	text             string
+	oldLine, newLine int
+}
+
+// diffLines returns the edit script turning a into b, or nil when they are equal, using the
+// longest common subsequence of the lines between their common prefix and suffix. It reports
+// false when those lines need a table of more than maxDiffCells.
+func diffLines(a, b []string) ([]diffOp, bool) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA) == 0 && len(midB) == 0 {
+		return nil, true
+	}
+	width := len(midB) + 1
+	if (len(midA)+1)*width > maxDiffCells {
+		return nil, false
+	}
+
+	// lcs[i*width+j] is the length of the longest common subsequence of midA[i:] and midB[j:].
+	lcs := make([]int32, (len(midA)+1)*width)
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else if lcs[(i+1)*width+j] >= lcs[i*width+j+1] {
+				lcs[i*width+j] = lcs[(i+1)*width+j]
+			} else {
+				lcs[i*width+j] = lcs[i*width+j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(midB))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{' ', a[i], i + 1, i + 1})
+	}
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			ops = append(ops, diffOp{' ', midA[i], prefix + i + 1, prefix + j + 1})
+			i, j = i+1, j+1
+		case j == len(midB) || (i < len(midA) && lcs[(i+1)*width+j] >= lcs[i*width+j+1]):
+			ops = append(ops, diffOp{'-', midA[i], prefix + i + 1, prefix + j + 1})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', midB[j], prefix + i + 1, prefix + j + 1})
+			j++
+		}
+	}
+	for k := suffix; k > 0; k-- {
+		ops = append(ops, diffOp{' ', a[len(a)-k], len(a) - k + 1, len(b) - k + 1})
+	}
+	return ops, true
+}
+
+// splitLines splits content into lines without their line endings.
+func splitLines(content []byte) []string {
+	text := strings.TrimSuffix(string(content), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// hunkRange formats the start,count range of a hunk header; an empty range starts at the line
+// before it, as in diff -u.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,51 @@
+// pkg/nodeprop/diff_test.go
+package nodeprop
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "name: CI\non: push\njobs:\n  a: 1\n  b: 2\n  c: 3\n  d: 4\n  e: 5\n  f: 6\n  g: 7\n  h: 8\n  i: 9\n"
+	new := strings.Replace(strings.Replace(old, "name: CI", "name: Build", 1), "  i: 9\n", "  i: 9\n  j: 10\n", 1)
+
+	expected := `--- a/ci.yml
++++ b/ci.yml
+@@ -1,4 +1,4 @@
+-name: CI
++name: Build
+ on: push
+ jobs:
+   a: 1
+@@ -10,3 +10,4 @@
+   g: 7
+   h: 8
+   i: 9
++  j: 10
+`
+	assert.Equal(t, expected, UnifiedDiff("ci.yml", []byte(old), []byte(new)), "Distant changes should be separate hunks")
+	assert.Empty(t, UnifiedDiff("ci.yml", []byte(old), []byte(old)), "Equal content has no diff")
+	assert.Equal(t, "--- a/new.yml\n+++ b/new.yml\n@@ -0,0 +1,2 @@\n+on: push\n+jobs: {}\n", UnifiedDiff("new.yml", nil, []byte("on: push\njobs: {}\n")), "New files are all additions")
+}
+
+func TestUnifiedDiffLargeFiles(t *testing.T) {
+	var old, new strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&old, "  step%d: old\n", i)
+		fmt.Fprintf(&new, "  step%d: new\n", i)
+	}
+
+	// A small change in a large file is diffed around the unchanged lines
+	edited := strings.Replace(old.String(), "  step10000: old\n", "  step10000: edited\n", 1)
+	diff := UnifiedDiff("big.yml", []byte(old.String()), []byte(edited))
+	assert.Contains(t, diff, "@@ -9998,7 +9998,7 @@\n")
+	assert.Contains(t, diff, "\n-  step10000: old\n+  step10000: edited\n")
+
+	// Rewriting every line is summarized instead of diffed
+	diff = UnifiedDiff("big.yml", []byte(old.String()), []byte(new.String()))
+	assert.Equal(t, "--- a/big.yml\n+++ b/big.yml\nFiles differ (20000 lines before, 20000 after); the change is too large to show\n", diff)
+}
@@ -0,0 +1,160 @@
+// pkg/nodeprop/discovery.go
+package nodeprop
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultNodePropGlob matches .nodeprop.yml files at any depth of a repository.
+const DefaultNodePropGlob = "**/.nodeprop.yml"
+
+// discoveryExcludedDirs are never descended into when discovering nodeprop files.
+var discoveryExcludedDirs = map[string]bool{
+	".git":         true,
+	"vendor":       true,
+	"node_modules": true,
+}
+
+// DiscoverNodePropFiles walks the repository at root and returns the slash-separated paths,
+// relative to root, of every file matching pattern (DefaultNodePropGlob when empty).
+// Patterns are matched per path segment and `**` matches any number of directories.
+func DiscoverNodePropFiles(root, pattern string) ([]string, error) {
+	if pattern == "" {
+		pattern = DefaultNodePropGlob
+	}
+	patternSegments := strings.Split(pattern, "/")
+
+	var found []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && discoveryExcludedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchGlobSegments(patternSegments, strings.Split(rel, "/")) {
+			found = append(found, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(found)
+	return found, nil
+}
+
+// LoadFleet discovers every nodeprop file under root and loads each of its documents, keyed by
+// the slash-separated directory of the file relative to root. Additional documents in a
+// multi-document file are keyed with a `#N` suffix.
+func LoadFleet(root, pattern string) (map[string]NodePropFile, error) {
+	paths, err := DiscoverNodePropFiles(root, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	fleet := make(map[string]NodePropFile)
+	for _, rel := range paths {
+		nodeProps, err := LoadNodePropFiles(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+
+		key := path.Dir(rel)
+		if key == "." {
+			key = filepath.Base(absRoot)
+		}
+		for i, nodeProp := range nodeProps {
+			id := key
+			if i > 0 {
+				id = fmt.Sprintf("%s#%d", key, i+1)
+			}
+			fleet[id] = nodeProp
+		}
+	}
+	return fleet, nil
+}
+
+// Service statuses with a meaning to nodeprop.
+const (
+	StatusActive   = "active"   // the default of generated files whose template sets no status
+	StatusArchived = "archived" // skipped by fleet commands unless asked otherwise
+)
+
+// ExcludeFromFleet removes from the fleet the members whose ID or name matches one of the
+// path.Match patterns and, unless includeArchived is set, archived members. It returns the
+// remaining fleet and, for each excluded member, the reason it was excluded.
+func ExcludeFromFleet(fleet map[string]NodePropFile, patterns []string, includeArchived bool) (map[string]NodePropFile, map[string]string, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, nil, fmt.Errorf("invalid exclude pattern '%s': %w", pattern, err)
+		}
+	}
+
+	kept := make(map[string]NodePropFile)
+	excluded := make(map[string]string)
+	for id, nodeProp := range fleet {
+		reason := ""
+		for _, pattern := range patterns {
+			idMatch, _ := path.Match(pattern, id)
+			nameMatch, _ := path.Match(pattern, nodeProp.Name)
+			if idMatch || nameMatch {
+				reason = fmt.Sprintf("matches exclude pattern '%s'", pattern)
+				break
+			}
+		}
+		if reason == "" && !includeArchived && nodeProp.Status == StatusArchived {
+			reason = "archived"
+		}
+
+		if reason != "" {
+			excluded[id] = reason
+		} else {
+			kept[id] = nodeProp
+		}
+	}
+	return kept, excluded, nil
+}
+
+// matchGlobSegments reports whether the path segments match the pattern segments,
+// where a `**` pattern segment matches zero or more path segments.
+func matchGlobSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchGlobSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchGlobSegments(pattern[1:], segments[1:])
+}
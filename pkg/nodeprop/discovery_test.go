@@ -0,0 +1,85 @@
+// pkg/nodeprop/discovery_test.go
+package nodeprop
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscoverNodePropFiles(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	// A monorepo with a root service, two nested services and a vendored copy that must be ignored
+	files := []string{
+		".nodeprop.yml",
+		"services/api/.nodeprop.yml",
+		"services/worker/.nodeprop.yml",
+		"services/api/README.md",
+		"vendor/github.com/other/.nodeprop.yml",
+		"web/node_modules/pkg/.nodeprop.yml",
+	}
+	for _, file := range files {
+		path := filepath.Join(repoPath, filepath.FromSlash(file))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		assert.NoError(t, err, "Failed to create directory for %s", file)
+		err = ioutil.WriteFile(path, []byte("id: \"\"\n"), 0644)
+		assert.NoError(t, err, "Failed to write %s", file)
+	}
+
+	found, err := DiscoverNodePropFiles(repoPath, "")
+	assert.NoError(t, err, "DiscoverNodePropFiles failed")
+	assert.Equal(t, []string{
+		".nodeprop.yml",
+		"services/api/.nodeprop.yml",
+		"services/worker/.nodeprop.yml",
+	}, found, "Discovered nodeprop files mismatch")
+
+	found, err = DiscoverNodePropFiles(repoPath, "services/*/.nodeprop.yml")
+	assert.NoError(t, err, "DiscoverNodePropFiles failed with custom glob")
+	assert.Equal(t, []string{
+		"services/api/.nodeprop.yml",
+		"services/worker/.nodeprop.yml",
+	}, found, "Custom glob should only match service directories")
+}
+
+func TestExcludeFromFleet(t *testing.T) {
+	fleet := map[string]NodePropFile{
+		"api":                {Name: "api", Status: "active"},
+		"experiments/search": {Name: "search-exp", Status: "active"},
+		"legacy":             {Name: "legacy", Status: StatusArchived},
+		"web":                {Name: "web", Status: "active"},
+	}
+
+	kept, excluded, err := ExcludeFromFleet(fleet, []string{"experiments/*", "w?b"}, false)
+	assert.NoError(t, err, "ExcludeFromFleet failed")
+	assert.Equal(t, map[string]NodePropFile{"api": fleet["api"]}, kept, "Only api should remain")
+	assert.Equal(t, map[string]string{
+		"experiments/search": "matches exclude pattern 'experiments/*'",
+		"legacy":             "archived",
+		"web":                "matches exclude pattern 'w?b'",
+	}, excluded, "Excluded members and reasons mismatch")
+
+	// Names match too, and archived members can be included
+	kept, excluded, err = ExcludeFromFleet(fleet, []string{"*-exp"}, true)
+	assert.NoError(t, err, "ExcludeFromFleet failed")
+	assert.Len(t, kept, 3)
+	assert.Equal(t, map[string]string{"experiments/search": "matches exclude pattern '*-exp'"}, excluded)
+
+	_, _, err = ExcludeFromFleet(fleet, []string{"[api"}, false)
+	assert.Error(t, err, "Malformed patterns should be rejected")
+}
+
+func TestServiceIdentity(t *testing.T) {
+	name, address := serviceIdentity(NodePropArguments{RepoPath: "/src/platform"})
+	assert.Equal(t, "platform", name, "Root service name mismatch")
+	assert.Equal(t, "https://github.com/Cdaprod/platform", address, "Root service address mismatch")
+
+	name, address = serviceIdentity(NodePropArguments{RepoPath: "/src/platform", Path: "services/api/"})
+	assert.Equal(t, "api", name, "Subdirectory service name mismatch")
+	assert.Equal(t, "https://github.com/Cdaprod/platform/tree/HEAD/services/api", address, "Subdirectory service address mismatch")
+}
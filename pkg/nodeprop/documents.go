@@ -0,0 +1,171 @@
+// pkg/nodeprop/documents.go
+package nodeprop
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// documentSeparator matches a YAML `---` document separator line.
+var documentSeparator = regexp.MustCompile(`^---(\s.*)?$`)
+
+// yamlChunk is the text between two `---` separator lines of a YAML stream: the separator
+// line that opens it (empty for the text before the first separator) and its body. Bodies that
+// are empty or hold only comments are not documents, but are kept so a stream can be written
+// back unchanged.
+type yamlChunk struct {
+	separator []byte
+	body      []byte
+	document  bool
+}
+
+// splitYAMLChunks splits content on `---` separator lines, keeping every byte. Unparseable
+// bodies count as documents so the caller can report them.
+func splitYAMLChunks(content []byte) []yamlChunk {
+	chunks := []yamlChunk{{}}
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if documentSeparator.Match(bytes.TrimRight(line, "\r\n")) {
+			chunks = append(chunks, yamlChunk{separator: line})
+			continue
+		}
+		last := &chunks[len(chunks)-1]
+		last.body = append(last.body, line...)
+	}
+
+	for i := range chunks {
+		var probe interface{}
+		err := yaml.Unmarshal(chunks[i].body, &probe)
+		chunks[i].document = err != nil || probe != nil
+	}
+	return chunks
+}
+
+// joinYAMLChunks writes chunks back into a YAML stream.
+func joinYAMLChunks(chunks []yamlChunk) []byte {
+	var out bytes.Buffer
+	for _, chunk := range chunks {
+		out.Write(chunk.separator)
+		out.Write(chunk.body)
+	}
+	return out.Bytes()
+}
+
+// splitYAMLDocuments returns the raw bytes of each document in content, skipping empty and
+// comment-only chunks.
+func splitYAMLDocuments(content []byte) [][]byte {
+	var documents [][]byte
+	for _, chunk := range splitYAMLChunks(content) {
+		if chunk.document {
+			documents = append(documents, chunk.body)
+		}
+	}
+	return documents
+}
+
+// replaceYAMLDocument replaces the raw bytes of the document at index in content, leaving
+// everything else, including comments, headers and separator lines, byte-for-byte untouched.
+// Comment lines opening the replaced document are kept too.
+// An index equal to the number of documents appends a new document.
+func replaceYAMLDocument(content []byte, index int, replacement []byte) ([]byte, error) {
+	if len(replacement) > 0 && replacement[len(replacement)-1] != '\n' {
+		replacement = append(replacement, '\n')
+	}
+
+	chunks := splitYAMLChunks(content)
+	documents := 0
+	for i := range chunks {
+		if !chunks[i].document {
+			continue
+		}
+		if documents == index {
+			chunks[i].body = append(leadingComments(chunks[i].body), replacement...)
+			return joinYAMLChunks(chunks), nil
+		}
+		documents++
+	}
+	if index != documents {
+		return nil, fmt.Errorf("nodeprop document %d out of range (file has %d)", index+1, documents)
+	}
+
+	// Append a new document, separated from any existing content.
+	out := append([]byte(nil), content...)
+	if len(bytes.TrimSpace(out)) > 0 {
+		if out[len(out)-1] != '\n' {
+			out = append(out, '\n')
+		}
+		out = append(out, "---\n"...)
+	}
+	return append(out, replacement...), nil
+}
+
+// leadingComments returns the blank and comment lines that open a document body, such as a
+// file header written above the first document.
+func leadingComments(body []byte) []byte {
+	n := 0
+	for _, line := range bytes.SplitAfter(body, []byte("\n")) {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 && trimmed[0] != '#' {
+			break
+		}
+		n += len(line)
+	}
+	return append([]byte(nil), body[:n]...)
+}
+
+// ParseNodePropDocuments parses a single- or multi-document .nodeprop.yml into one
+// NodePropFile per document, validating each.
+func ParseNodePropDocuments(content []byte) ([]NodePropFile, error) {
+	documents := splitYAMLDocuments(content)
+	nodeProps := make([]NodePropFile, 0, len(documents))
+	for i, document := range documents {
+		var nodeProp NodePropFile
+		if err := yaml.Unmarshal(document, &nodeProp); err != nil {
+			return nil, fmt.Errorf("invalid nodeprop document %d: %w", i+1, err)
+		}
+		nodeProps = append(nodeProps, nodeProp)
+	}
+	return nodeProps, nil
+}
+
+// LoadNodePropFiles reads the .nodeprop.yml at path and parses every document in it.
+func LoadNodePropFiles(path string) ([]NodePropFile, error) {
+	return loadNodePropFiles(ioutil.ReadFile, path)
+}
+
+// loadNodePropFiles is LoadNodePropFiles reading through the manager's filesystem.
+func (npm *NodePropManager) loadNodePropFiles(path string) ([]NodePropFile, error) {
+	return loadNodePropFiles(npm.readFile, path)
+}
+
+func loadNodePropFiles(readFile func(string) ([]byte, error), path string) ([]NodePropFile, error) {
+	content, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	nodeProps, err := ParseNodePropDocuments(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse '%s': %w", path, err)
+	}
+	return nodeProps, nil
+}
+
+// ReplaceNodePropDocument replaces the document at index in a single- or multi-document
+// .nodeprop.yml with nodeProp, leaving every other document, comment and separator line
+// byte-for-byte untouched. An index equal to the number of documents appends a new document.
+func ReplaceNodePropDocument(content []byte, index int, nodeProp NodePropFile) ([]byte, error) {
+	if _, err := ParseNodePropDocuments(content); err != nil {
+		return nil, err
+	}
+	replacement, err := yaml.Marshal(&nodeProp)
+	if err != nil {
+		return nil, err
+	}
+	return replaceYAMLDocument(content, index, replacement)
+}
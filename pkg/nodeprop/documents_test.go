@@ -0,0 +1,112 @@
+// pkg/nodeprop/documents_test.go
+package nodeprop
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const twoDocumentNodeProp = `# services sharing one repository
+id: "api-id"
+name: "api"
+status: "active"
+custom_properties:
+  domain: "api.test.domain"
+---
+# the worker keeps its own comments
+id: "worker-id"
+name: "worker"
+status: "inactive"
+custom_properties:
+  domain: "worker.test.domain"
+`
+
+func TestLoadNodePropFilesMultiDocument(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	nodePropPath := filepath.Join(repoPath, ".nodeprop.yml")
+	err := ioutil.WriteFile(nodePropPath, []byte(twoDocumentNodeProp), 0644)
+	assert.NoError(t, err, "Failed to write .nodeprop.yml")
+
+	nodeProps, err := LoadNodePropFiles(nodePropPath)
+	assert.NoError(t, err, "LoadNodePropFiles failed")
+	assert.Len(t, nodeProps, 2, "Both documents should be parsed")
+	if len(nodeProps) == 2 {
+		assert.Equal(t, "api", nodeProps[0].Name, "First document name mismatch")
+		assert.Equal(t, "worker", nodeProps[1].Name, "Second document name mismatch")
+		assert.Equal(t, "worker.test.domain", nodeProps[1].CustomProperties.Domain, "Second document domain mismatch")
+	}
+
+	// Single-document files are handled transparently, including a leading separator
+	nodeProps, err = ParseNodePropDocuments([]byte("---\nid: \"single\"\n"))
+	assert.NoError(t, err, "ParseNodePropDocuments failed")
+	assert.Len(t, nodeProps, 1, "Single document should be parsed")
+
+	// An invalid document is reported by position
+	_, err = ParseNodePropDocuments([]byte("id: \"ok\"\n---\nmetadata:\n  github:\n    stars: many\n"))
+	assert.ErrorContains(t, err, "document 2", "Error should name the invalid document")
+}
+
+func TestReplaceNodePropDocumentPreservesOthers(t *testing.T) {
+	nodeProps, err := ParseNodePropDocuments([]byte(twoDocumentNodeProp))
+	assert.NoError(t, err, "ParseNodePropDocuments failed")
+
+	updated := nodeProps[0]
+	updated.Status = "archived"
+
+	content, err := ReplaceNodePropDocument([]byte(twoDocumentNodeProp), 0, updated)
+	assert.NoError(t, err, "ReplaceNodePropDocument failed")
+
+	secondDocument := twoDocumentNodeProp[strings.Index(twoDocumentNodeProp, "# the worker"):]
+	assert.True(t, strings.HasSuffix(string(content), "---\n"+secondDocument), "Second document should be preserved byte-for-byte")
+
+	nodeProps, err = ParseNodePropDocuments(content)
+	assert.NoError(t, err, "Updated file should parse")
+	assert.Len(t, nodeProps, 2, "Updated file should keep both documents")
+	if len(nodeProps) == 2 {
+		assert.Equal(t, "archived", nodeProps[0].Status, "First document should be updated")
+		assert.Equal(t, "inactive", nodeProps[1].Status, "Second document should be unchanged")
+	}
+}
+
+func TestReplaceNodePropDocumentPreservesLayout(t *testing.T) {
+	content := `# fleet file, edited by hand
+--- # api
+id: "api-id"
+name: "api"
+---
+# retired services are kept below for reference
+--- # worker
+id: "worker-id"
+name: "worker"
+`
+	nodeProps, err := ParseNodePropDocuments([]byte(content))
+	assert.NoError(t, err, "ParseNodePropDocuments failed")
+	assert.Len(t, nodeProps, 2, "Header and comment-only chunks are not documents")
+
+	updated := nodeProps[1]
+	updated.Status = "archived"
+	replaced, err := ReplaceNodePropDocument([]byte(content), 1, updated)
+	assert.NoError(t, err, "ReplaceNodePropDocument failed")
+
+	prefix := content[:strings.Index(content, "id: \"worker-id\"")]
+	assert.True(t, strings.HasPrefix(string(replaced), prefix), "Everything before the replaced document should be untouched")
+	nodeProps, err = ParseNodePropDocuments(replaced)
+	assert.NoError(t, err)
+	if assert.Len(t, nodeProps, 2) {
+		assert.Equal(t, "archived", nodeProps[1].Status)
+	}
+
+	// Appending separates the new document from the existing content
+	appended, err := ReplaceNodePropDocument([]byte(content), 2, NodePropFile{Name: "web"})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(appended), content+"---\n"), "Existing content should be untouched when appending")
+
+	_, err = ReplaceNodePropDocument([]byte(content), 3, NodePropFile{})
+	assert.ErrorContains(t, err, "out of range")
+}
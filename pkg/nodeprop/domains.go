@@ -0,0 +1,311 @@
+// pkg/nodeprop/domains.go
+package nodeprop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultDNSTimeout bounds each DNS lookup of the domains analyzer when no Timeout is set.
+const defaultDNSTimeout = 5 * time.Second
+
+// DomainResolver looks up DNS records for the domains analyzer; *net.Resolver satisfies it.
+type DomainResolver interface {
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// DomainsAnalyzer flags services claiming the same CustomProperties.Domain and domains that are
+// not valid hostnames. When Resolver is set, each domain whose service's network has an expected
+// target (a CNAME or an IP address) is also resolved and compared against it.
+type DomainsAnalyzer struct {
+	Resolver DomainResolver
+	Expected map[string]string // network -> expected CNAME target or IP
+	Timeout  time.Duration     // bound of each lookup; defaults to 5s
+}
+
+// Name implements Analyzer.
+func (DomainsAnalyzer) Name() string {
+	return "domains"
+}
+
+// Analyze implements Analyzer.
+func (a DomainsAnalyzer) Analyze(fleet map[string]NodePropFile) []Finding {
+	var findings []Finding
+	claims := map[string][]string{}
+	var domains []string
+
+	for _, service := range sortedServices(fleet) {
+		domain := fleet[service].CustomProperties.Domain
+		if domain == "" {
+			continue
+		}
+		if err := ValidateDomain(domain); err != nil {
+			findings = append(findings, Finding{
+				Analyzer: a.Name(),
+				Severity: SeverityError,
+				Resource: domain,
+				Message:  fmt.Sprintf("invalid domain '%s': %v", domain, err),
+				Services: []string{service},
+			})
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSuffix(domain, "."))
+		if _, seen := claims[key]; !seen {
+			domains = append(domains, key)
+		}
+		claims[key] = append(claims[key], service)
+	}
+
+	sort.Strings(domains)
+	for _, domain := range domains {
+		services := claims[domain]
+		if len(services) < 2 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Analyzer: a.Name(),
+			Severity: severityFor(fleet, services),
+			Resource: domain,
+			Message:  fmt.Sprintf("domain '%s' is claimed by %d services: %s", domain, len(services), strings.Join(services, ", ")),
+			Services: services,
+		})
+	}
+
+	if a.Resolver != nil {
+		for _, domain := range domains {
+			for _, service := range claims[domain] {
+				expected := a.Expected[fleet[service].CustomProperties.Network]
+				if expected == "" {
+					continue
+				}
+				if message := a.checkDNS(domain, expected); message != "" {
+					findings = append(findings, Finding{
+						Analyzer: a.Name(),
+						Severity: SeverityWarning,
+						Resource: domain,
+						Message:  message,
+						Services: []string{service},
+					})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// checkDNS resolves domain and describes how it differs from the expected target, or returns
+// an empty string when it matches. Lookups use the fully qualified name so the resolver's search
+// domains never apply, and give up after Timeout so an unresponsive server cannot stall a run.
+func (a DomainsAnalyzer) checkDNS(domain, expected string) string {
+	timeout := a.Timeout
+	if timeout <= 0 {
+		timeout = defaultDNSTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	fqdn := domain + "."
+
+	if net.ParseIP(expected) != nil {
+		addrs, err := a.Resolver.LookupHost(ctx, fqdn)
+		if err != nil {
+			return fmt.Sprintf("failed to resolve '%s': %v", domain, err)
+		}
+		for _, addr := range addrs {
+			if net.ParseIP(addr).Equal(net.ParseIP(expected)) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("domain '%s' resolves to %s, expected %s", domain, strings.Join(addrs, ", "), expected)
+	}
+
+	cname, err := a.Resolver.LookupCNAME(ctx, fqdn)
+	if err != nil {
+		return fmt.Sprintf("failed to resolve '%s': %v", domain, err)
+	}
+	cname = strings.TrimSuffix(cname, ".")
+	if !strings.EqualFold(cname, strings.TrimSuffix(expected, ".")) {
+		return fmt.Sprintf("domain '%s' points at %s, expected %s", domain, cname, expected)
+	}
+	return ""
+}
+
+// ValidateDomain strictly validates a fully qualified hostname: at least two LDH labels of at most
+// 63 characters, at most 253 characters overall and a non-numeric top-level label. A leading "*"
+// wildcard label and a trailing root dot are allowed. Internationalized names must be given in
+// their punycode (xn--) form, and those labels must decode to Unicode.
+func ValidateDomain(domain string) error {
+	name := strings.TrimSuffix(domain, ".")
+	if name == "" {
+		return errors.New("domain is empty")
+	}
+	if len(name) > 253 {
+		return fmt.Errorf("domain is %d characters long, the maximum is 253", len(name))
+	}
+	for _, r := range name {
+		if r >= utf8.RuneSelf {
+			return errors.New("internationalized domains must be punycode-encoded (xn--)")
+		}
+	}
+
+	labels := strings.Split(strings.ToLower(name), ".")
+	if len(labels) < 2 {
+		return errors.New("domain must have at least two labels")
+	}
+	for i, label := range labels {
+		if label == "*" && i == 0 {
+			continue
+		}
+		if err := validateDomainLabel(label); err != nil {
+			return fmt.Errorf("label '%s': %w", label, err)
+		}
+	}
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return errors.New("top-level label must not be numeric")
+	}
+	return nil
+}
+
+// validateDomainLabel checks a single lowercase label.
+func validateDomainLabel(label string) error {
+	if label == "" {
+		return errors.New("label is empty")
+	}
+	if len(label) > 63 {
+		return fmt.Errorf("label is %d characters long, the maximum is 63", len(label))
+	}
+	for _, r := range label {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return fmt.Errorf("invalid character %q", r)
+		}
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return errors.New("label must not start or end with a hyphen")
+	}
+	if len(label) >= 4 && label[2:4] == "--" {
+		if !strings.HasPrefix(label, "xn--") {
+			return errors.New("hyphens in the third and fourth positions are reserved for punycode (xn--)")
+		}
+		decoded, err := decodePunycode(label[4:])
+		if err != nil {
+			return fmt.Errorf("invalid punycode: %w", err)
+		}
+		if strings.IndexFunc(decoded, func(r rune) bool { return r >= utf8.RuneSelf }) < 0 {
+			return errors.New("punycode label does not encode any non-ASCII characters")
+		}
+		if i := strings.IndexFunc(decoded, func(r rune) bool { return !unicode.IsGraphic(r) }); i >= 0 {
+			return fmt.Errorf("punycode label encodes the non-printable character %U", []rune(decoded[i:])[0])
+		}
+	}
+	return nil
+}
+
+// Punycode parameters from RFC 3492.
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+)
+
+// decodePunycode decodes the part of an A-label after the "xn--" prefix (RFC 3492).
+func decodePunycode(encoded string) (string, error) {
+	var output []rune
+	rest := encoded
+	if b := strings.LastIndex(encoded, "-"); b >= 0 {
+		for _, r := range encoded[:b] {
+			output = append(output, r)
+		}
+		rest = encoded[b+1:]
+	}
+	if rest == "" {
+		return "", errors.New("no encoded characters")
+	}
+
+	n, i, bias := punycodeInitialN, 0, punycodeInitialBias
+	for pos := 0; pos < len(rest); {
+		oldi, w := i, 1
+		for k := punycodeBase; ; k += punycodeBase {
+			if pos >= len(rest) {
+				return "", errors.New("truncated input")
+			}
+			digit, ok := punycodeDigit(rest[pos])
+			pos++
+			if !ok {
+				return "", fmt.Errorf("invalid digit %q", rest[pos-1])
+			}
+			if digit > (math.MaxInt32-i)/w {
+				return "", errors.New("overflow")
+			}
+			i += digit * w
+
+			t := k - bias
+			if k <= bias {
+				t = punycodeTMin
+			} else if k >= bias+punycodeTMax {
+				t = punycodeTMax
+			}
+			if digit < t {
+				break
+			}
+			if w > math.MaxInt32/(punycodeBase-t) {
+				return "", errors.New("overflow")
+			}
+			w *= punycodeBase - t
+		}
+
+		bias = punycodeAdapt(i-oldi, len(output)+1, oldi == 0)
+		if i/(len(output)+1) > math.MaxInt32-n {
+			return "", errors.New("overflow")
+		}
+		n += i / (len(output) + 1)
+		i %= len(output) + 1
+		if n > utf8.MaxRune || !utf8.ValidRune(rune(n)) {
+			return "", errors.New("encodes an invalid code point")
+		}
+		output = append(output[:i], append([]rune{rune(n)}, output[i:]...)...)
+		i++
+	}
+	return string(output), nil
+}
+
+// punycodeDigit returns the value of a base-36 punycode digit.
+func punycodeDigit(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c-'0') + 26, true
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c - 'A'), true
+	}
+	return 0, false
+}
+
+// punycodeAdapt is the RFC 3492 bias adaptation function.
+func punycodeAdapt(delta, numPoints int, first bool) int {
+	if first {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
@@ -0,0 +1,204 @@
+// pkg/nodeprop/domains_test.go
+package nodeprop
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeResolver answers DNS lookups from fixed records.
+type fakeResolver struct {
+	cnames map[string]string
+	hosts  map[string][]string
+}
+
+func (r fakeResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	if cname, ok := r.cnames[host]; ok {
+		return cname, nil
+	}
+	return "", errors.New("no such host")
+}
+
+func (r fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestValidateDomain(t *testing.T) {
+	valid := []string{
+		"api.cdaprod.dev",
+		"API.Cdaprod.dev.",
+		"*.cdaprod.dev",
+		"my-service.eu-west-1.cdaprod.dev",
+		"xn--mnchen-3ya.de",
+		"xn--bcher-kva.example",
+	}
+	for _, domain := range valid {
+		assert.NoError(t, ValidateDomain(domain), "Expected %s to be valid", domain)
+	}
+
+	invalid := []string{
+		"",
+		"localhost",
+		"münchen.de",
+		"api_v2.cdaprod.dev",
+		"-api.cdaprod.dev",
+		"api-.cdaprod.dev",
+		"api..cdaprod.dev",
+		"api.*.cdaprod.dev",
+		"ab--cd.cdaprod.dev",
+		"xn--abc.cdaprod.dev",
+		"xn--mnchen-3y.de",
+		"xn--99999999.cdaprod.dev",
+		"10.0.0.1",
+		"a123456789012345678901234567890123456789012345678901234567890123.dev",
+	}
+	for _, domain := range invalid {
+		assert.Error(t, ValidateDomain(domain), "Expected %s to be invalid", domain)
+	}
+}
+
+func TestDecodePunycode(t *testing.T) {
+	decoded, err := decodePunycode("mnchen-3ya")
+	assert.NoError(t, err, "decodePunycode failed")
+	assert.Equal(t, "münchen", decoded, "Decoded label mismatch")
+
+	decoded, err = decodePunycode("wgv71a119e")
+	assert.NoError(t, err, "decodePunycode failed")
+	assert.Equal(t, "日本語", decoded, "Decoded label mismatch")
+}
+
+func TestDomainsAnalyzer(t *testing.T) {
+	fleet := map[string]NodePropFile{
+		"api":    {Status: "active", CustomProperties: CustomProperties{Network: "backend", Domain: "api.cdaprod.dev"}},
+		"api-v2": {Status: "active", CustomProperties: CustomProperties{Network: "backend", Domain: "API.cdaprod.dev."}},
+		"old":    {Status: "inactive", CustomProperties: CustomProperties{Domain: "web.cdaprod.dev"}},
+		"web":    {Status: "active", CustomProperties: CustomProperties{Network: "frontend", Domain: "web.cdaprod.dev"}},
+		"broken": {Status: "active", CustomProperties: CustomProperties{Domain: "broken_domain.dev"}},
+		"docs":   {Status: "active", CustomProperties: CustomProperties{Network: "frontend", Domain: "docs.cdaprod.dev"}},
+	}
+
+	findings, err := RunAnalyzers(fleet, "domains")
+	assert.NoError(t, err, "RunAnalyzers failed")
+	assert.Equal(t, []Finding{
+		{
+			Analyzer: "domains",
+			Severity: SeverityError,
+			Resource: "broken_domain.dev",
+			Message:  "invalid domain 'broken_domain.dev': label 'broken_domain': invalid character '_'",
+			Services: []string{"broken"},
+		},
+		{
+			Analyzer: "domains",
+			Severity: SeverityError,
+			Resource: "api.cdaprod.dev",
+			Message:  "domain 'api.cdaprod.dev' is claimed by 2 services: api, api-v2",
+			Services: []string{"api", "api-v2"},
+		},
+		{
+			Analyzer: "domains",
+			Severity: SeverityWarning,
+			Resource: "web.cdaprod.dev",
+			Message:  "domain 'web.cdaprod.dev' is claimed by 2 services: old, web",
+			Services: []string{"old", "web"},
+		},
+	}, findings, "Domain findings mismatch")
+}
+
+func TestDomainsAnalyzerDNS(t *testing.T) {
+	fleet := map[string]NodePropFile{
+		"api":  {CustomProperties: CustomProperties{Network: "backend", Domain: "api.cdaprod.dev"}},
+		"jobs": {CustomProperties: CustomProperties{Network: "backend", Domain: "jobs.cdaprod.dev"}},
+		"web":  {CustomProperties: CustomProperties{Network: "frontend", Domain: "web.cdaprod.dev"}},
+		"docs": {CustomProperties: CustomProperties{Network: "frontend", Domain: "docs.cdaprod.dev"}},
+		"misc": {CustomProperties: CustomProperties{Network: "other", Domain: "misc.cdaprod.dev"}},
+	}
+	analyzer := DomainsAnalyzer{
+		Resolver: fakeResolver{
+			cnames: map[string]string{
+				"api.cdaprod.dev.":  "lb.cdaprod.dev.",
+				"jobs.cdaprod.dev.": "old-lb.cdaprod.dev.",
+			},
+			hosts: map[string][]string{
+				"web.cdaprod.dev.": {"10.0.0.2", "203.0.113.10"},
+			},
+		},
+		Expected: map[string]string{
+			"backend":  "lb.cdaprod.dev",
+			"frontend": "203.0.113.10",
+		},
+	}
+
+	findings := analyzer.Analyze(fleet)
+	assert.Equal(t, []Finding{
+		{
+			Analyzer: "domains",
+			Severity: SeverityWarning,
+			Resource: "docs.cdaprod.dev",
+			Message:  "failed to resolve 'docs.cdaprod.dev': no such host",
+			Services: []string{"docs"},
+		},
+		{
+			Analyzer: "domains",
+			Severity: SeverityWarning,
+			Resource: "jobs.cdaprod.dev",
+			Message:  "domain 'jobs.cdaprod.dev' points at old-lb.cdaprod.dev, expected lb.cdaprod.dev",
+			Services: []string{"jobs"},
+		},
+	}, findings, "DNS findings mismatch")
+}
+
+// slowResolver answers no lookup until its context is done.
+type slowResolver struct{}
+
+func (slowResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func (slowResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestDomainsAnalyzerDNSTimeout(t *testing.T) {
+	fleet := map[string]NodePropFile{
+		"api": {CustomProperties: CustomProperties{Network: "backend", Domain: "api.cdaprod.dev"}},
+	}
+	analyzer := DomainsAnalyzer{
+		Resolver: slowResolver{},
+		Expected: map[string]string{"backend": "lb.cdaprod.dev"},
+		Timeout:  10 * time.Millisecond,
+	}
+
+	start := time.Now()
+	findings := analyzer.Analyze(fleet)
+	assert.Less(t, time.Since(start), time.Second, "A lookup should give up at the configured timeout")
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, "failed to resolve 'api.cdaprod.dev': context deadline exceeded", findings[0].Message)
+	}
+}
+
+func TestRunAnalyzersWithOverride(t *testing.T) {
+	fleet := map[string]NodePropFile{
+		"api": {CustomProperties: CustomProperties{Network: "backend", Domain: "api.cdaprod.dev"}},
+	}
+	override := DomainsAnalyzer{
+		Resolver: fakeResolver{cnames: map[string]string{"api.cdaprod.dev.": "old-lb.cdaprod.dev."}},
+		Expected: map[string]string{"backend": "lb.cdaprod.dev"},
+	}
+
+	findings, err := RunAnalyzersWith(fleet, []Analyzer{override}, "domains")
+	assert.NoError(t, err, "RunAnalyzersWith failed")
+	assert.Len(t, findings, 1, "The configured analyzer should resolve the domain")
+
+	findings, err = RunAnalyzers(fleet, "domains")
+	assert.NoError(t, err, "RunAnalyzers failed")
+	assert.Empty(t, findings, "The built-in analyzer should be left unconfigured")
+}
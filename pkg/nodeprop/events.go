@@ -0,0 +1,58 @@
+// pkg/nodeprop/events.go
+package nodeprop
+
+// eventBufferSize is the capacity of each subscriber's channel.
+const eventBufferSize = 64
+
+// EventEmitter publishes events to any number of subscribers.
+type EventEmitter interface {
+	// Subscribe returns a channel receiving every event emitted from now on and a func that
+	// unsubscribes and closes the channel. The func may be called more than once.
+	Subscribe() (<-chan Event, func())
+	// Emit publishes an event to every current subscriber.
+	Emit(event Event)
+}
+
+var _ EventEmitter = (*NodePropManager)(nil)
+
+// Subscribe implements EventEmitter.
+func (npm *NodePropManager) Subscribe() (<-chan Event, func()) {
+	npm.subscribersMu.Lock()
+	defer npm.subscribersMu.Unlock()
+
+	if npm.subscribers == nil {
+		npm.subscribers = make(map[int]chan Event)
+	}
+	id := npm.nextSubscriberID
+	npm.nextSubscriberID++
+	ch := make(chan Event, eventBufferSize)
+	npm.subscribers[id] = ch
+
+	unsubscribe := func() {
+		npm.subscribersMu.Lock()
+		defer npm.subscribersMu.Unlock()
+		// Emit sends while holding the read lock, so the channel is never closed mid-send.
+		if ch, ok := npm.subscribers[id]; ok {
+			delete(npm.subscribers, id)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// Emit implements EventEmitter. Emitting never blocks: an event is dropped for a subscriber
+// whose buffer is full.
+func (npm *NodePropManager) Emit(event Event) {
+	npm.subscribersMu.RLock()
+	defer npm.subscribersMu.RUnlock()
+
+	for _, ch := range npm.subscribers {
+		select {
+		case ch <- event:
+		default:
+			if npm.Logger != nil {
+				npm.Logger.Warnf("Dropping %s event for a slow subscriber: %s", event.Type, event.Message)
+			}
+		}
+	}
+}
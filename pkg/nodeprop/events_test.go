@@ -0,0 +1,128 @@
+// pkg/nodeprop/events_test.go
+package nodeprop
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribeAndEmit(t *testing.T) {
+	npManager := &NodePropManager{
+		Logger: logrus.New(),
+	}
+
+	first, unsubscribeFirst := npManager.Subscribe()
+	second, unsubscribeSecond := npManager.Subscribe()
+	defer unsubscribeSecond()
+
+	event := Event{Type: EventTypeInfo, Message: "hello"}
+	npManager.Emit(event)
+	assert.Equal(t, event, <-first, "First subscriber should receive the event")
+	assert.Equal(t, event, <-second, "Second subscriber should receive the event")
+
+	// Unsubscribing closes the channel and is safe to repeat
+	unsubscribeFirst()
+	unsubscribeFirst()
+	_, open := <-first
+	assert.False(t, open, "Channel should be closed after unsubscribing")
+
+	npManager.Emit(Event{Type: EventTypeSuccess, Message: "done"})
+	assert.Equal(t, "done", (<-second).Message, "Remaining subscriber should still receive events")
+}
+
+func TestEmitDoesNotBlockOnFullSubscriber(t *testing.T) {
+	npManager := &NodePropManager{
+		Logger: logrus.New(),
+	}
+
+	ch, unsubscribe := npManager.Subscribe()
+	defer unsubscribe()
+
+	for i := 0; i < eventBufferSize+10; i++ {
+		npManager.Emit(Event{Type: EventTypeInfo, Message: "tick"})
+	}
+	assert.Len(t, ch, eventBufferSize, "Events beyond the buffer should be dropped")
+}
+
+func TestUnsubscribeRacesWithEmit(t *testing.T) {
+	npManager := &NodePropManager{
+		Logger: logrus.New(),
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				npManager.Emit(Event{Type: EventTypeInfo, Message: "tick"})
+			}
+		}
+	}()
+
+	var subscribers sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		subscribers.Add(1)
+		go func() {
+			defer subscribers.Done()
+			ch, unsubscribe := npManager.Subscribe()
+			<-ch
+			unsubscribe()
+			for range ch {
+				// Drain anything buffered before the close
+			}
+		}()
+	}
+	subscribers.Wait()
+	close(stop)
+	wg.Wait()
+}
+
+func TestEventJSON(t *testing.T) {
+	line, err := json.Marshal(Event{Type: EventTypeError, Message: "failed"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"error","message":"failed"}`, string(line))
+}
+
+func BenchmarkEmit(b *testing.B) {
+	for _, subscribers := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("subscribers=%d", subscribers), func(b *testing.B) {
+			npManager := &NodePropManager{}
+			var drained sync.WaitGroup
+			unsubscribes := make([]func(), 0, subscribers)
+			for i := 0; i < subscribers; i++ {
+				events, unsubscribe := npManager.Subscribe()
+				unsubscribes = append(unsubscribes, unsubscribe)
+				drained.Add(1)
+				go func() {
+					defer drained.Done()
+					for range events {
+					}
+				}()
+			}
+			event := Event{Type: EventTypeSuccess, Message: "added workflow 'ci'"}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				npManager.Emit(event)
+			}
+			b.StopTimer()
+
+			// Unsubscribing closes the channels, so the drain goroutines exit before the next run
+			for _, unsubscribe := range unsubscribes {
+				unsubscribe()
+			}
+			drained.Wait()
+		})
+	}
+}
@@ -0,0 +1,359 @@
+// pkg/nodeprop/files.go
+package nodeprop
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriteFS receives the changes the manager makes to repositories.
+type WriteFS interface {
+	MkdirAll(path string, perm os.FileMode) error
+	WriteFile(name string, data []byte, perm os.FileMode) error // replaces name atomically
+	Remove(name string) error
+}
+
+// OSFS writes to the local filesystem. It is what the manager uses when Files is nil.
+type OSFS struct{}
+
+// MkdirAll implements WriteFS.
+func (OSFS) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+
+// WriteFile implements WriteFS by writing a temporary file next to name and renaming it over
+// name, so readers never see a partly written file.
+func (OSFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return writeFileAtomic(name, data, perm)
+}
+
+// Remove implements WriteFS.
+func (OSFS) Remove(name string) error { return os.Remove(name) }
+
+// Kinds of FileChange.
+const (
+	FileChangeMkdir  = "mkdir"
+	FileChangeWrite  = "write"
+	FileChangeRemove = "remove"
+)
+
+// FileChange is a change recorded by RecordingFS.
+type FileChange struct {
+	Kind string // mkdir, write or remove
+	Path string
+	Data []byte // the content written
+}
+
+// RecordingFS records the changes made through it without applying them, for dry runs. It
+// records every change as given, without looking at the filesystem; the manager checks that
+// the files it removes exist.
+type RecordingFS struct {
+	mu      sync.Mutex
+	changes []FileChange
+}
+
+// MkdirAll implements WriteFS.
+func (r *RecordingFS) MkdirAll(path string, _ os.FileMode) error {
+	r.record(FileChange{Kind: FileChangeMkdir, Path: path})
+	return nil
+}
+
+// WriteFile implements WriteFS.
+func (r *RecordingFS) WriteFile(name string, data []byte, _ os.FileMode) error {
+	r.record(FileChange{Kind: FileChangeWrite, Path: name, Data: append([]byte(nil), data...)})
+	return nil
+}
+
+// Remove implements WriteFS.
+func (r *RecordingFS) Remove(name string) error {
+	r.record(FileChange{Kind: FileChangeRemove, Path: name})
+	return nil
+}
+
+// Changes returns the changes recorded so far, in order.
+func (r *RecordingFS) Changes() []FileChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]FileChange(nil), r.changes...)
+}
+
+func (r *RecordingFS) record(change FileChange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, change)
+}
+
+// MemFS is an in-memory WriteFS whose files can be read back through io/fs, for tests and for
+// previewing runs without a checkout. Paths given to its WriteFS methods are made absolute,
+// and read through io/fs without the leading slash, e.g. "src/api/.nodeprop.yml". Its zero
+// value is an empty filesystem ready to use.
+type MemFS struct {
+	mu    sync.RWMutex
+	files map[string]*memFile // by io/fs path; directories have fs.ModeDir set
+}
+
+// memFile is a file or directory of a MemFS.
+type memFile struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// MkdirAll implements WriteFS.
+func (m *MemFS) MkdirAll(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	for dir := fsPath(path); dir != "."; dir = fsDir(dir) {
+		if file, ok := m.files[dir]; ok {
+			if !file.mode.IsDir() {
+				return &fs.PathError{Op: "mkdir", Path: path, Err: fs.ErrExist}
+			}
+			break
+		}
+		m.files[dir] = &memFile{mode: fs.ModeDir | perm, modTime: time.Now()}
+	}
+	return nil
+}
+
+// WriteFile implements WriteFS. Like the local filesystem, it fails when the directory of name
+// does not exist.
+func (m *MemFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	path := fsPath(name)
+	if dir := fsDir(path); dir != "." {
+		if file, ok := m.files[dir]; !ok || !file.mode.IsDir() {
+			return &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+	}
+	if file, ok := m.files[path]; ok && file.mode.IsDir() {
+		return &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	}
+	m.files[path] = &memFile{data: append([]byte(nil), data...), mode: perm, modTime: time.Now()}
+	return nil
+}
+
+// Remove implements WriteFS.
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path := fsPath(name)
+	if _, ok := m.files[path]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	prefix := path + "/"
+	for other := range m.files {
+		if strings.HasPrefix(other, prefix) {
+			return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrExist}
+		}
+	}
+	delete(m.files, path)
+	return nil
+}
+
+// Open implements fs.FS.
+func (m *MemFS) Open(name string) (fs.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	info, err := m.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		entries, _ := m.readDir("open", name)
+		return &memDir{info: info, entries: entries}, nil
+	}
+	return &memOpenFile{info: info, Reader: bytes.NewReader(info.file.data)}, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	info, err := m.stat("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	return append([]byte(nil), info.file.data...), nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.readDir("readdir", name)
+}
+
+// Stat implements fs.StatFS.
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	info, err := m.stat("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (m *MemFS) init() {
+	if m.files == nil {
+		m.files = map[string]*memFile{}
+	}
+}
+
+// stat returns the memFileInfo of the io/fs path name; the root always exists.
+func (m *MemFS) stat(op, name string) (*memFileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &memFileInfo{name: ".", file: &memFile{mode: fs.ModeDir | 0755}}, nil
+	}
+	file, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return &memFileInfo{name: name[strings.LastIndex(name, "/")+1:], file: file}, nil
+}
+
+// readDir returns the entries of the directory name, sorted by name.
+func (m *MemFS) readDir(op, name string) ([]fs.DirEntry, error) {
+	info, err := m.stat(op, name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: op, Path: name, Err: errors.New("not a directory")}
+	}
+	var entries []fs.DirEntry
+	for path, file := range m.files {
+		if fsDir(path) == name {
+			entries = append(entries, &memFileInfo{name: path[strings.LastIndex(path, "/")+1:], file: file})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// memFileInfo describes a memFile as both an fs.FileInfo and an fs.DirEntry.
+type memFileInfo struct {
+	name string
+	file *memFile
+}
+
+func (i *memFileInfo) Name() string               { return i.name }
+func (i *memFileInfo) Size() int64                { return int64(len(i.file.data)) }
+func (i *memFileInfo) Mode() fs.FileMode          { return i.file.mode }
+func (i *memFileInfo) Type() fs.FileMode          { return i.file.mode.Type() }
+func (i *memFileInfo) ModTime() time.Time         { return i.file.modTime }
+func (i *memFileInfo) IsDir() bool                { return i.file.mode.IsDir() }
+func (i *memFileInfo) Sys() interface{}           { return nil }
+func (i *memFileInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// memOpenFile is an open regular file of a MemFS. Its content is not affected by later writes.
+type memOpenFile struct {
+	info *memFileInfo
+	*bytes.Reader
+}
+
+func (f *memOpenFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memOpenFile) Close() error               { return nil }
+
+// memDir is an open directory of a MemFS.
+type memDir struct {
+	info    *memFileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n > 0 && len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < len(rest) {
+		rest = rest[:n]
+	}
+	d.offset += len(rest)
+	return rest, nil
+}
+
+// fsPath turns a local path into the io/fs path of a filesystem rooted at /: absolute,
+// slash-separated and without the leading slash.
+func fsPath(name string) string {
+	if abs, err := filepath.Abs(name); err == nil {
+		name = abs
+	}
+	name = strings.TrimPrefix(filepath.ToSlash(name), filepath.ToSlash(filepath.VolumeName(name)))
+	if name = strings.Trim(name, "/"); name == "" {
+		return "."
+	}
+	return name
+}
+
+// fsDir returns the parent of an io/fs path.
+func fsDir(path string) string {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[:i]
+	}
+	return "."
+}
+
+// files returns the WriteFS the manager writes through: Files, or the local filesystem. A dry
+// run without Files discards its changes.
+func (npm *NodePropManager) files() WriteFS {
+	if npm.Files != nil {
+		return npm.Files
+	}
+	if npm.DryRun {
+		return &RecordingFS{}
+	}
+	return OSFS{}
+}
+
+// readFS returns the filesystem the manager reads repositories from, as an io/fs filesystem
+// rooted at /: Files when it can be read, such as a MemFS, and the local filesystem otherwise.
+func (npm *NodePropManager) readFS() fs.FS {
+	if fsys, ok := npm.Files.(fs.FS); ok {
+		return fsys
+	}
+	return os.DirFS("/")
+}
+
+// readFile reads the file at the local path name through readFS.
+func (npm *NodePropManager) readFile(name string) ([]byte, error) {
+	return fs.ReadFile(npm.readFS(), fsPath(name))
+}
+
+// statFile returns the FileInfo of the file at the local path name through readFS.
+func (npm *NodePropManager) statFile(name string) (fs.FileInfo, error) {
+	return fs.Stat(npm.readFS(), fsPath(name))
+}
+
+// repoFS returns the directory dir, such as a repository, of readFS as a filesystem of its own.
+func (npm *NodePropManager) repoFS(dir string) fs.FS {
+	sub, err := fs.Sub(npm.readFS(), fsPath(dir))
+	if err != nil {
+		return os.DirFS(dir)
+	}
+	return sub
+}
@@ -0,0 +1,152 @@
+// pkg/nodeprop/files_test.go
+package nodeprop
+
+import (
+	"context"
+	"crypto/ed25519"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOSFSWriteFile(t *testing.T) {
+	dir := setupTempRepo(t)
+	defer teardownTempRepo(t, dir)
+
+	path := filepath.Join(dir, "nested", "file.yml")
+	assert.NoError(t, OSFS{}.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, OSFS{}.WriteFile(path, []byte("one"), 0644))
+	assert.NoError(t, OSFS{}.WriteFile(path, []byte("two"), 0644), "Existing files should be replaced")
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(content))
+	entries, err := ioutil.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "No temporary files should be left behind")
+}
+
+func TestRecordingFSDryRun(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	workflow := []byte("name: CI\non: push\n")
+	workflowPath := filepath.Join(repoPath, ".github", "workflows", "ci.yml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(workflowPath), 0755))
+	assert.NoError(t, ioutil.WriteFile(workflowPath, workflow, 0644))
+	nodePropPath := filepath.Join(repoPath, ".nodeprop.yml")
+	assert.NoError(t, ioutil.WriteFile(nodePropPath, []byte("name: api\n"), 0644))
+
+	catalogPath := filepath.Join(repoPath, "catalog.yml")
+	recorder := &RecordingFS{}
+	npManager := &NodePropManager{Logger: logrus.New(), Files: recorder, DryRun: true, CatalogPath: catalogPath}
+	assert.NoError(t, npManager.DeprecateWorkflow(context.Background(), NodePropArguments{RepoPath: repoPath, Workflow: "ci"}, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.NoError(t, npManager.DeleteNodeProp(context.Background(), repoPath))
+
+	changes := recorder.Changes()
+	if assert.Len(t, changes, 3) {
+		assert.Equal(t, FileChange{Kind: FileChangeWrite, Path: workflowPath, Data: DeprecateWorkflowContent(workflow, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))}, changes[0])
+		assert.Equal(t, FileChangeWrite, changes[1].Kind)
+		assert.Equal(t, nodePropPath, changes[1].Path)
+		assert.Equal(t, FileChange{Kind: FileChangeRemove, Path: nodePropPath}, changes[2], "The missing signature should not be removed")
+	}
+
+	content, err := ioutil.ReadFile(workflowPath)
+	assert.NoError(t, err)
+	assert.Equal(t, workflow, content, "A dry run should not modify the workflow")
+	assert.FileExists(t, nodePropPath, "A dry run should not delete the nodeprop file")
+	assert.NoFileExists(t, catalogPath, "A dry run should not update the catalog")
+}
+
+func TestRecordingFSRemoveDoesNotTouchDisk(t *testing.T) {
+	recorder := &RecordingFS{}
+	assert.NoError(t, recorder.Remove("/nonexistent/.nodeprop.yml"), "Removals should be recorded as given")
+	assert.Equal(t, []FileChange{{Kind: FileChangeRemove, Path: "/nonexistent/.nodeprop.yml"}}, recorder.Changes())
+}
+
+func TestMemFS(t *testing.T) {
+	memFS := &MemFS{}
+	assert.NoError(t, memFS.MkdirAll("/repos/api/.github/workflows", 0755))
+	assert.NoError(t, memFS.WriteFile("/repos/api/.github/workflows/ci.yml", []byte("name: CI\n"), 0644))
+	assert.NoError(t, memFS.WriteFile("/repos/api/.nodeprop.yml", []byte("name: api\n"), 0644))
+	assert.Error(t, memFS.WriteFile("/repos/web/.nodeprop.yml", nil, 0644), "Writing into a missing directory should fail")
+
+	assert.NoError(t, fstest.TestFS(memFS, "repos/api/.nodeprop.yml", "repos/api/.github/workflows/ci.yml"))
+	content, err := fs.ReadFile(memFS, "repos/api/.nodeprop.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, "name: api\n", string(content))
+
+	assert.Error(t, memFS.Remove("/repos/api/.github"), "Non-empty directories should not be removed")
+	assert.NoError(t, memFS.Remove("/repos/api/.nodeprop.yml"))
+	_, err = fs.Stat(memFS, "repos/api/.nodeprop.yml")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.True(t, os.IsNotExist(memFS.Remove("/repos/api/.nodeprop.yml")), "Removing a missing file should fail like os.Remove")
+}
+
+func TestAddWorkflowMemFS(t *testing.T) {
+	memFS := &MemFS{}
+	npManager := &NodePropManager{
+		GlobalNodePropPath: filepath.Join("..", "..", "assets", ".empty.nodeprop.yml"),
+		CatalogPath:        "/catalog/nodes.yml",
+		Files:              memFS,
+		Logger:             logrus.New(),
+	}
+	result, err := npManager.AddWorkflowWithResult(NodePropArguments{RepoPath: "/repos/api", Workflow: "ci", Template: "go-ci"})
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+	assert.Equal(t, WorkflowCreated, result.Action)
+
+	_, err = os.Stat("/repos/api")
+	assert.True(t, os.IsNotExist(err), "Nothing should be written to the local filesystem")
+
+	// The generated file is read back from the in-memory filesystem
+	nodeProps, err := npManager.loadNodePropFiles("/repos/api/.nodeprop.yml")
+	assert.NoError(t, err, "Failed to read .nodeprop.yml")
+	if assert.Len(t, nodeProps[0].Metadata.Workflows, 1, "The written workflow should be discovered") {
+		assert.Equal(t, ".github/workflows/ci.yml", nodeProps[0].Metadata.Workflows[0].File)
+		assert.True(t, nodeProps[0].Metadata.Workflows[0].Managed)
+	}
+
+	catalog, err := loadCatalog(memFS.ReadFile, "catalog/nodes.yml")
+	assert.NoError(t, err)
+	if assert.Len(t, catalog.Nodes, 1, "The catalog should be written through the in-memory filesystem") {
+		assert.Equal(t, "api", catalog.Nodes[0].Name)
+	}
+
+	// Signing writes through the in-memory filesystem too, replacing a stale detached signature
+	_, npManager.SigningKey, err = ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, memFS.WriteFile("/repos/api/.nodeprop.yml.sig", []byte("stale\n"), 0644))
+	assert.NoError(t, npManager.SignFile(context.Background(), "/repos/api/.nodeprop.yml"))
+	nodeProps, err = npManager.loadNodePropFiles("/repos/api/.nodeprop.yml")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, nodeProps[0].Metadata.Signature, "The file should be signed inline")
+	_, err = fs.Stat(memFS, "repos/api/.nodeprop.yml.sig")
+	assert.ErrorIs(t, err, fs.ErrNotExist, "The stale detached signature should be removed")
+}
+
+func TestAddWorkflowMemFSRequireFile(t *testing.T) {
+	memFS := &MemFS{}
+	assert.NoError(t, memFS.MkdirAll("/repos/api", 0755))
+	assert.NoError(t, memFS.WriteFile("/repos/api/go.mod", []byte("module example.com/api\n"), 0644))
+	npManager := &NodePropManager{
+		GlobalNodePropPath: filepath.Join("..", "..", "assets", ".empty.nodeprop.yml"),
+		Files:              memFS,
+		Logger:             logrus.New(),
+	}
+
+	// The conditions are checked on the in-memory filesystem the workflow is written to
+	result, err := npManager.AddWorkflowWithResult(NodePropArguments{RepoPath: "/repos/api", Workflow: "node-ci", RequireFile: "package.json"})
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+	assert.Equal(t, WorkflowSkipped, result.Action, "A file missing from the in-memory filesystem should skip the workflow")
+
+	result, err = npManager.AddWorkflowWithResult(NodePropArguments{RepoPath: "/repos/api", Workflow: "go-ci", RequireFile: "go.mod", Template: "go-ci"})
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+	assert.Equal(t, WorkflowCreated, result.Action, "A file in the in-memory filesystem should satisfy RequireFile")
+}
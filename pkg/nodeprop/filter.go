@@ -0,0 +1,514 @@
+// pkg/nodeprop/filter.go
+package nodeprop
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// filterFieldAliases are shorthands for commonly filtered fields.
+var filterFieldAliases = map[string]string{
+	"domain":  "custom_properties.domain",
+	"network": "custom_properties.network",
+	"image":   "custom_properties.image",
+	"owner":   "metadata.owner",
+	"tags":    "metadata.tags",
+	"stars":   "metadata.github.stars",
+	"forks":   "metadata.github.forks",
+	"issues":  "metadata.github.issues",
+	"license": "metadata.github.license",
+	"topics":  "metadata.github.topics",
+}
+
+// FilterError reports a malformed filter expression and the 1-based position of the problem.
+type FilterError struct {
+	Expr string
+	Pos  int
+	Msg  string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("invalid filter at position %d: %s\n  %s\n  %s^", e.Pos, e.Msg, e.Expr, strings.Repeat(" ", e.Pos-1))
+}
+
+// Filter is a parsed filter expression over NodePropFile fields, such as
+//
+//	status == "active" && "docker" in capabilities && domain =~ "\.cdaprod\.dev$"
+//
+// Operands are string, number or boolean literals and fields named by their YAML path
+// (metadata.github.stars) or a shorthand alias (stars). Supported operators are ==, !=, in
+// (list membership or substring), =~ (regular expression), <, <=, >, >= (numbers), &&, || and !,
+// with parentheses for grouping.
+type Filter struct {
+	root filterNode
+}
+
+// ParseFilter parses a filter expression, returning a *FilterError pointing at the offending
+// position when it is malformed or names an unknown field.
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{expr: expr, tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != filterEOF {
+		return nil, p.errorAt(tok, fmt.Sprintf("unexpected %s", tok))
+	}
+	return &Filter{root: root}, nil
+}
+
+// Match reports whether the nodeprop file satisfies the filter.
+func (f *Filter) Match(nodeProp NodePropFile) (bool, error) {
+	return f.root.eval(reflect.ValueOf(nodeProp))
+}
+
+// FilterFleet returns the members of the fleet matching the filter.
+func FilterFleet(fleet map[string]NodePropFile, filter *Filter) (map[string]NodePropFile, error) {
+	matched := make(map[string]NodePropFile)
+	for id, nodeProp := range fleet {
+		ok, err := filter.Match(nodeProp)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		if ok {
+			matched[id] = nodeProp
+		}
+	}
+	return matched, nil
+}
+
+// Lexer
+
+type filterTokenKind int
+
+const (
+	filterEOF filterTokenKind = iota
+	filterIdent
+	filterString
+	filterNumber
+	filterOp
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string // operator or identifier text, unquoted string, number literal
+	pos  int    // 1-based
+}
+
+func (t filterToken) String() string {
+	switch t.kind {
+	case filterEOF:
+		return "end of expression"
+	case filterString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("'%s'", t.text)
+	}
+}
+
+// filterOperators are matched longest first.
+var filterOperators = []string{"&&", "||", "==", "!=", "=~", "<=", ">=", "<", ">", "!", "(", ")"}
+
+func lexFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, &FilterError{Expr: expr, Pos: i + 1, Msg: "unterminated string"}
+			}
+			// Backslashes are kept literally except before a quote, so regular expressions
+			// such as "\.dev$" need no double escaping.
+			text := strings.ReplaceAll(expr[i+1:end], `\"`, `"`)
+			tokens = append(tokens, filterToken{kind: filterString, text: text, pos: i + 1})
+			i = end + 1
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(expr) && expr[i+1] >= '0' && expr[i+1] <= '9':
+			end := i + 1
+			for end < len(expr) && (expr[end] >= '0' && expr[end] <= '9' || expr[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, filterToken{kind: filterNumber, text: expr[i:end], pos: i + 1})
+			i = end
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			end := i + 1
+			for end < len(expr) && (expr[end] == '_' || expr[end] == '.' || expr[end] >= 'a' && expr[end] <= 'z' ||
+				expr[end] >= 'A' && expr[end] <= 'Z' || expr[end] >= '0' && expr[end] <= '9') {
+				end++
+			}
+			tokens = append(tokens, filterToken{kind: filterIdent, text: expr[i:end], pos: i + 1})
+			i = end
+		default:
+			matched := false
+			for _, op := range filterOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, filterToken{kind: filterOp, text: op, pos: i + 1})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &FilterError{Expr: expr, Pos: i + 1, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+		}
+	}
+	return append(tokens, filterToken{kind: filterEOF, pos: len(expr) + 1}), nil
+}
+
+// Parser
+
+type filterParser struct {
+	expr   string
+	tokens []filterToken
+	next   int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.next]
+}
+
+func (p *filterParser) take() filterToken {
+	tok := p.tokens[p.next]
+	if tok.kind != filterEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *filterParser) isOp(text string) bool {
+	tok := p.peek()
+	return tok.kind == filterOp && tok.text == text
+}
+
+func (p *filterParser) errorAt(tok filterToken, msg string) error {
+	return &FilterError{Expr: p.expr, Pos: tok.pos, Msg: msg}
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		p.take()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		p.take()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = filterAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.isOp("!") {
+		p.take()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{operand}, nil
+	}
+	if p.isOp("(") {
+		open := p.take()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, p.errorAt(p.peek(), fmt.Sprintf("expected ')' to close the '(' at position %d, found %s", open.pos, p.peek()))
+		}
+		p.take()
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	opTok := p.peek()
+	op := opTok.text
+	switch {
+	case opTok.kind == filterIdent && op == "in":
+	case opTok.kind == filterOp && (op == "==" || op == "!=" || op == "=~" || op == "<" || op == "<=" || op == ">" || op == ">="):
+	default:
+		// A bare boolean field such as `custom_properties.auto_scale` is a comparison with true.
+		if field, ok := left.(filterField); ok && field.kind == reflect.Bool {
+			return filterCompare{op: "==", left: left, right: filterLiteral{value: true}}, nil
+		}
+		return nil, p.errorAt(opTok, fmt.Sprintf("expected a comparison operator, found %s", opTok))
+	}
+	p.take()
+
+	rightTok := p.peek()
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	compare := filterCompare{op: op, left: left, right: right}
+	switch op {
+	case "=~":
+		literal, ok := right.(filterLiteral)
+		pattern, isString := literal.value.(string)
+		if !ok || !isString {
+			return nil, p.errorAt(rightTok, "the right side of =~ must be a string literal")
+		}
+		if compare.pattern, err = regexp.Compile(pattern); err != nil {
+			return nil, p.errorAt(rightTok, fmt.Sprintf("invalid regular expression: %v", err))
+		}
+	case "in":
+		if field, ok := right.(filterField); ok && field.kind != reflect.Slice && field.kind != reflect.String {
+			return nil, p.errorAt(rightTok, fmt.Sprintf("the right side of in must be a list or string, %s is not", field.path))
+		}
+	}
+	return compare, nil
+}
+
+func (p *filterParser) parseOperand() (filterNode, error) {
+	tok := p.take()
+	switch tok.kind {
+	case filterString:
+		return filterLiteral{value: tok.text}, nil
+	case filterNumber:
+		number, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorAt(tok, fmt.Sprintf("invalid number %s", tok))
+		}
+		return filterLiteral{value: number}, nil
+	case filterIdent:
+		switch tok.text {
+		case "true", "false":
+			return filterLiteral{value: tok.text == "true"}, nil
+		case "in":
+			return nil, p.errorAt(tok, "expected a field or literal, found 'in'")
+		}
+		field, err := resolveFilterField(tok.text)
+		if err != nil {
+			return nil, p.errorAt(tok, err.Error())
+		}
+		return field, nil
+	default:
+		return nil, p.errorAt(tok, fmt.Sprintf("expected a field or literal, found %s", tok))
+	}
+}
+
+// resolveFilterField resolves a YAML path or alias to the NodePropFile field it names.
+func resolveFilterField(name string) (filterField, error) {
+	path := name
+	if alias, ok := filterFieldAliases[name]; ok {
+		path = alias
+	}
+
+	t := reflect.TypeOf(NodePropFile{})
+	var index []int
+	for _, segment := range strings.Split(path, ".") {
+		if t.Kind() != reflect.Struct {
+			return filterField{}, fmt.Errorf("unknown field '%s'", name)
+		}
+		found := false
+		for i := 0; i < t.NumField(); i++ {
+			tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+			if tag == segment {
+				index = append(index, i)
+				t = t.Field(i).Type
+				found = true
+				break
+			}
+		}
+		if !found {
+			return filterField{}, fmt.Errorf("unknown field '%s'", name)
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64:
+	case reflect.Slice:
+		if elem := t.Elem().Kind(); elem != reflect.String && elem != reflect.Int {
+			return filterField{}, fmt.Errorf("field '%s' is not a list of scalars", name)
+		}
+	default:
+		return filterField{}, fmt.Errorf("field '%s' is not a scalar or list", name)
+	}
+	return filterField{path: path, index: index, kind: t.Kind()}, nil
+}
+
+// AST
+
+type filterNode interface {
+	eval(nodeProp reflect.Value) (bool, error)
+}
+
+type filterOr struct{ left, right filterNode }
+type filterAnd struct{ left, right filterNode }
+type filterNot struct{ operand filterNode }
+
+type filterCompare struct {
+	op          string
+	left, right filterNode
+	pattern     *regexp.Regexp // for =~
+}
+
+type filterLiteral struct {
+	value interface{} // string, float64 or bool
+}
+
+type filterField struct {
+	path  string
+	index []int
+	kind  reflect.Kind
+}
+
+func (n filterOr) eval(v reflect.Value) (bool, error) {
+	ok, err := n.left.eval(v)
+	if err != nil || ok {
+		return ok, err
+	}
+	return n.right.eval(v)
+}
+
+func (n filterAnd) eval(v reflect.Value) (bool, error) {
+	ok, err := n.left.eval(v)
+	if err != nil || !ok {
+		return ok, err
+	}
+	return n.right.eval(v)
+}
+
+func (n filterNot) eval(v reflect.Value) (bool, error) {
+	ok, err := n.operand.eval(v)
+	return !ok, err
+}
+
+// Literals and fields are operands, never evaluated as conditions on their own.
+func (filterLiteral) eval(reflect.Value) (bool, error) {
+	return false, fmt.Errorf("a literal is not a condition")
+}
+
+func (filterField) eval(reflect.Value) (bool, error) {
+	return false, fmt.Errorf("a field is not a condition")
+}
+
+// operandValue returns a literal's value, or a field's value as a string, float64, bool or
+// []interface{} of those.
+func operandValue(n filterNode, v reflect.Value) interface{} {
+	switch operand := n.(type) {
+	case filterLiteral:
+		return operand.value
+	case filterField:
+		return filterScalar(v.FieldByIndex(operand.index))
+	}
+	return nil
+}
+
+func filterScalar(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Slice:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = filterScalar(v.Index(i))
+		}
+		return items
+	}
+	return nil
+}
+
+func (n filterCompare) eval(v reflect.Value) (bool, error) {
+	left, right := operandValue(n.left, v), operandValue(n.right, v)
+
+	switch n.op {
+	case "==":
+		return filterEqual(left, right), nil
+	case "!=":
+		return !filterEqual(left, right), nil
+	case "=~":
+		s, ok := left.(string)
+		if !ok {
+			return false, fmt.Errorf("=~ needs a string on the left, got %v", left)
+		}
+		return n.pattern.MatchString(s), nil
+	case "in":
+		switch container := right.(type) {
+		case []interface{}:
+			for _, item := range container {
+				if filterEqual(left, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case string:
+			s, ok := left.(string)
+			return ok && strings.Contains(container, s), nil
+		}
+		return false, fmt.Errorf("in needs a list or string on the right, got %v", right)
+	default:
+		a, aok := left.(float64)
+		b, bok := right.(float64)
+		if !aok || !bok {
+			return false, fmt.Errorf("%s needs numbers on both sides, got %v and %v", n.op, left, right)
+		}
+		switch n.op {
+		case "<":
+			return a < b, nil
+		case "<=":
+			return a <= b, nil
+		case ">":
+			return a > b, nil
+		default:
+			return a >= b, nil
+		}
+	}
+}
+
+// filterEqual compares scalars, treating numbers numerically and everything else by its text.
+func filterEqual(a, b interface{}) bool {
+	if x, ok := a.(float64); ok {
+		if y, ok := b.(float64); ok {
+			return x == y
+		}
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
@@ -0,0 +1,83 @@
+// pkg/nodeprop/filter_test.go
+package nodeprop
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterMatch(t *testing.T) {
+	api := NodePropFile{
+		Status:       "active",
+		Capabilities: []string{"docker", "go"},
+		Metadata: Metadata{
+			Tags:   []string{"backend"},
+			GitHub: GitHub{Stars: 42, License: "MIT"},
+		},
+		CustomProperties: CustomProperties{Domain: "api.cdaprod.dev", AutoScale: true},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`status == "active" && "docker" in capabilities && domain =~ "\.cdaprod\.dev$"`, true},
+		{`status != "active"`, false},
+		{`stars > 10 && stars <= 42`, true},
+		{`metadata.github.stars >= 100 || license == "MIT"`, true},
+		{`!("python" in capabilities)`, true},
+		{`("python" in capabilities || "go" in capabilities) && tags == tags`, true},
+		{`custom_properties.auto_scale`, true},
+		{`custom_properties.monitoring_enabled == true`, false},
+		{`"cdaprod" in domain`, true},
+		{`domain =~ "^web\."`, false},
+	}
+	for _, tt := range tests {
+		filter, err := ParseFilter(tt.expr)
+		if !assert.NoError(t, err, tt.expr) {
+			continue
+		}
+		got, err := filter.Match(api)
+		assert.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, got, tt.expr)
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+	}{
+		{`status == "active`, 11},
+		{`status = "active"`, 8},
+		{`colour == "red"`, 1},
+		{`(status == "active"`, 20},
+		{`status == "active" &&`, 22},
+		{`domain =~ "("`, 11},
+		{`metadata == "x"`, 1},
+		{`"a" in stars`, 8},
+	}
+	for _, tt := range tests {
+		_, err := ParseFilter(tt.expr)
+		var filterErr *FilterError
+		if assert.True(t, errors.As(err, &filterErr), "%s should fail to parse", tt.expr) {
+			assert.Equal(t, tt.pos, filterErr.Pos, tt.expr)
+		}
+	}
+}
+
+func TestFilterFleet(t *testing.T) {
+	fleet := map[string]NodePropFile{
+		"api": {Status: "active"},
+		"web": {Status: "inactive"},
+	}
+	filter, err := ParseFilter(`status == "active"`)
+	assert.NoError(t, err)
+
+	matched, err := FilterFleet(fleet, filter)
+	assert.NoError(t, err)
+	assert.Len(t, matched, 1)
+	assert.Contains(t, matched, "api")
+}
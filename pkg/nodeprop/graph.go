@@ -0,0 +1,102 @@
+// pkg/nodeprop/graph.go
+package nodeprop
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GraphNode is a repository or service in the dependency graph.
+type GraphNode struct {
+	ID    string
+	Label string
+}
+
+// GraphEdge connects two services sharing a resource such as a network or domain.
+type GraphEdge struct {
+	From     string
+	To       string
+	Resource string // "network" or "domain"
+	Value    string
+}
+
+// Graph is the dependency graph of a fleet of nodeprop files.
+type Graph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+// BuildDependencyGraph builds a graph with one node per nodeprop file, keyed by the map key,
+// and an edge between every pair of services sharing a network or domain. Nodes and edges
+// are sorted so the output is stable.
+func (npm *NodePropManager) BuildDependencyGraph(files map[string]NodePropFile) Graph {
+	ids := make([]string, 0, len(files))
+	for id := range files {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var graph Graph
+	for _, id := range ids {
+		label := files[id].Name
+		if label == "" {
+			label = id
+		}
+		graph.Nodes = append(graph.Nodes, GraphNode{ID: id, Label: label})
+	}
+
+	for i, from := range ids {
+		for _, to := range ids[i+1:] {
+			a, b := files[from].CustomProperties, files[to].CustomProperties
+			if a.Network != "" && a.Network == b.Network {
+				graph.Edges = append(graph.Edges, GraphEdge{From: from, To: to, Resource: "network", Value: a.Network})
+			}
+			if a.Domain != "" && a.Domain == b.Domain {
+				graph.Edges = append(graph.Edges, GraphEdge{From: from, To: to, Resource: "domain", Value: a.Domain})
+			}
+		}
+	}
+	return graph
+}
+
+// DOT renders the graph in Graphviz DOT format.
+func (g Graph) DOT() string {
+	var out strings.Builder
+	out.WriteString("graph nodeprop {\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&out, "  %q [label=%q];\n", node.ID, node.Label)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&out, "  %q -- %q [label=%q];\n", edge.From, edge.To, edge.Resource+": "+edge.Value)
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart.
+func (g Graph) Mermaid() string {
+	aliases := make(map[string]string, len(g.Nodes))
+	var out strings.Builder
+	out.WriteString("graph LR\n")
+	for i, node := range g.Nodes {
+		aliases[node.ID] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&out, "  %s[\"%s\"]\n", aliases[node.ID], strings.ReplaceAll(node.Label, `"`, "#quot;"))
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&out, "  %s ---|\"%s: %s\"| %s\n", aliases[edge.From], edge.Resource, strings.ReplaceAll(edge.Value, `"`, "#quot;"), aliases[edge.To])
+	}
+	return out.String()
+}
+
+// Render renders the graph in the given format, "dot" or "mermaid".
+func (g Graph) Render(format string) (string, error) {
+	switch format {
+	case "dot":
+		return g.DOT(), nil
+	case "mermaid":
+		return g.Mermaid(), nil
+	default:
+		return "", fmt.Errorf("unsupported graph format '%s' (expected dot or mermaid)", format)
+	}
+}
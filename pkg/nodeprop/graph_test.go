@@ -0,0 +1,61 @@
+// pkg/nodeprop/graph_test.go
+package nodeprop
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDependencyGraph(t *testing.T) {
+	npManager := &NodePropManager{
+		Logger: logrus.New(),
+	}
+
+	files := map[string]NodePropFile{
+		"api": {
+			Name:             "api",
+			CustomProperties: CustomProperties{Network: "backend", Domain: "api.cdaprod.dev"},
+		},
+		"worker": {
+			Name:             "worker",
+			CustomProperties: CustomProperties{Network: "backend"},
+		},
+		"web": {
+			Name:             "web",
+			CustomProperties: CustomProperties{Network: "frontend", Domain: "api.cdaprod.dev"},
+		},
+		"docs": {
+			CustomProperties: CustomProperties{Network: "static"},
+		},
+	}
+
+	graph := npManager.BuildDependencyGraph(files)
+
+	expectedDOT := `graph nodeprop {
+  "api" [label="api"];
+  "docs" [label="docs"];
+  "web" [label="web"];
+  "worker" [label="worker"];
+  "api" -- "web" [label="domain: api.cdaprod.dev"];
+  "api" -- "worker" [label="network: backend"];
+}
+`
+	assert.Equal(t, expectedDOT, graph.DOT(), "DOT output mismatch")
+
+	expectedMermaid := `graph LR
+  n0["api"]
+  n1["docs"]
+  n2["web"]
+  n3["worker"]
+  n0 ---|"domain: api.cdaprod.dev"| n2
+  n0 ---|"network: backend"| n3
+`
+	output, err := graph.Render("mermaid")
+	assert.NoError(t, err, "Render failed")
+	assert.Equal(t, expectedMermaid, output, "Mermaid output mismatch")
+
+	_, err = graph.Render("svg")
+	assert.Error(t, err, "Unsupported formats should be rejected")
+}
@@ -0,0 +1,82 @@
+// pkg/nodeprop/ids.go
+package nodeprop
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator generates the IDs of nodeprop files.
+type IDGenerator interface {
+	NewID() string
+}
+
+// UUIDGenerator generates random (version 4) UUIDs. It is the default.
+type UUIDGenerator struct{}
+
+// NewID implements IDGenerator.
+func (UUIDGenerator) NewID() string {
+	return uuid.New().String()
+}
+
+// crockfordBase32 is the ULID alphabet.
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator generates ULIDs, which sort lexicographically by creation time.
+type ULIDGenerator struct {
+	Now func() time.Time // defaults to time.Now
+}
+
+// NewID implements IDGenerator.
+func (g ULIDGenerator) NewID() string {
+	now := time.Now
+	if g.Now != nil {
+		now = g.Now
+	}
+
+	// 48-bit millisecond timestamp followed by 80 random bits.
+	var id [16]byte
+	ms := uint64(now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		id[i] = byte(ms >> (40 - 8*i))
+	}
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+
+	// Encode the 128 bits as 26 base32 characters, the first holding only the top 3 bits.
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordBase32[id[15]&0x1f]
+		// Shift the whole 128-bit value right by 5 bits.
+		for j := 15; j > 0; j-- {
+			id[j] = id[j]>>5 | id[j-1]<<3
+		}
+		id[0] >>= 5
+	}
+	return string(out[:])
+}
+
+// NewIDGenerator returns the generator named by the `id_generator` config key: "uuid"
+// (the default when empty) or "ulid".
+func NewIDGenerator(name string) (IDGenerator, error) {
+	switch name {
+	case "", "uuid":
+		return UUIDGenerator{}, nil
+	case "ulid":
+		return ULIDGenerator{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported id generator '%s' (expected uuid or ulid)", name)
+	}
+}
+
+// newID generates an ID with the manager's generator, falling back to random UUIDs.
+func (npm *NodePropManager) newID() string {
+	if npm.IDs == nil {
+		return UUIDGenerator{}.NewID()
+	}
+	return npm.IDs.NewID()
+}
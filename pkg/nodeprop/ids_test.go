@@ -0,0 +1,46 @@
+// pkg/nodeprop/ids_test.go
+package nodeprop
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedIDGenerator always generates the same ID.
+type fixedIDGenerator string
+
+func (g fixedIDGenerator) NewID() string {
+	return string(g)
+}
+
+func TestULIDGenerator(t *testing.T) {
+	now := time.UnixMilli(1469918176385)
+	generator := ULIDGenerator{Now: func() time.Time { return now }}
+
+	id := generator.NewID()
+	assert.Len(t, id, 26, "ULID length mismatch")
+	assert.True(t, strings.HasPrefix(id, "01ARYZ6S41"), "ULID timestamp prefix mismatch: %s", id)
+	for _, c := range id {
+		assert.Contains(t, crockfordBase32, string(c), "ULID contains a character outside the Crockford alphabet")
+	}
+	assert.NotEqual(t, id, generator.NewID(), "ULIDs with the same timestamp should differ")
+
+	later := ULIDGenerator{Now: func() time.Time { return now.Add(time.Millisecond) }}.NewID()
+	assert.Less(t, id, later, "ULIDs should sort by creation time")
+}
+
+func TestNewIDGenerator(t *testing.T) {
+	generator, err := NewIDGenerator("")
+	assert.NoError(t, err, "NewIDGenerator failed")
+	assert.Equal(t, UUIDGenerator{}, generator, "Default generator should be UUIDGenerator")
+
+	generator, err = NewIDGenerator("ulid")
+	assert.NoError(t, err, "NewIDGenerator failed")
+	assert.Equal(t, ULIDGenerator{}, generator, "Expected ULIDGenerator")
+
+	_, err = NewIDGenerator("ksuid")
+	assert.Error(t, err, "Expected an error for an unsupported generator")
+}
@@ -0,0 +1,88 @@
+// pkg/nodeprop/locks.go
+package nodeprop
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// repoLock serializes mutating operations on one repository. refs counts the holders and
+// waiters so unused locks can be dropped.
+type repoLock struct {
+	sem  chan struct{}
+	refs int
+}
+
+// RepoKey returns the `owner/repo` key identifying the repository at repoPath. repoPath may
+// be relative or a directory inside the repository, such as a monorepo service: the key is
+// that of the enclosing git working tree, or of repoPath itself outside of one.
+func RepoKey(repoPath string) string {
+	return strings.TrimPrefix(RepoAddress(repoRoot(repoPath)), "https://github.com/")
+}
+
+// repoRoot returns the absolute path of the top level of the git working tree containing
+// path, or the absolute path itself when it is not inside one.
+func repoRoot(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	for dir := abs; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return abs
+		}
+		dir = parent
+	}
+}
+
+// LockRepo blocks until no other operation of the manager holds the repository at repoPath,
+// or until ctx is done. Operations on different repositories do not wait for each other.
+// The mutating methods of the manager take this lock themselves and must not be called while
+// holding it. The returned unlock function is safe to call more than once.
+func (npm *NodePropManager) LockRepo(ctx context.Context, repoPath string) (func(), error) {
+	key := RepoKey(repoPath)
+
+	npm.repoLocksMu.Lock()
+	if npm.repoLocks == nil {
+		npm.repoLocks = make(map[string]*repoLock)
+	}
+	lock, ok := npm.repoLocks[key]
+	if !ok {
+		lock = &repoLock{sem: make(chan struct{}, 1)}
+		npm.repoLocks[key] = lock
+	}
+	lock.refs++
+	npm.repoLocksMu.Unlock()
+
+	release := func() {
+		npm.repoLocksMu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(npm.repoLocks, key)
+		}
+		npm.repoLocksMu.Unlock()
+	}
+
+	select {
+	case lock.sem <- struct{}{}:
+	case <-ctx.Done():
+		release()
+		return nil, fmt.Errorf("gave up waiting for another operation on %s: %w", key, ctx.Err())
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-lock.sem
+			release()
+		})
+	}, nil
+}
@@ -0,0 +1,133 @@
+// pkg/nodeprop/locks_test.go
+package nodeprop
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockRepoSerializesSameRepo(t *testing.T) {
+	npManager := &NodePropManager{}
+
+	// Unsynchronized state is only safe if operations on the same repository never overlap;
+	// run with -race to check.
+	var inside, maxInside, total int
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := npManager.LockRepo(context.Background(), "/src/api")
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer unlock()
+			inside++
+			if inside > maxInside {
+				maxInside = inside
+			}
+			time.Sleep(time.Millisecond)
+			total++
+			inside--
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 20, total)
+	assert.Equal(t, 1, maxInside, "Operations on the same repository should not overlap")
+	assert.Empty(t, npManager.repoLocks, "Released locks should be dropped")
+}
+
+func TestLockRepoOtherReposProceed(t *testing.T) {
+	npManager := &NodePropManager{}
+
+	unlock, err := npManager.LockRepo(context.Background(), "/src/api")
+	assert.NoError(t, err)
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	unlockWeb, err := npManager.LockRepo(ctx, "/src/web")
+	assert.NoError(t, err, "Another repository should not wait")
+	unlockWeb()
+	unlockWeb()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = npManager.LockRepo(ctx, "/elsewhere/api")
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "Checkouts of the same owner/repo should share the lock")
+}
+
+func TestRepoKeyResolvesRepoRoot(t *testing.T) {
+	tempDir := setupTempRepo(t)
+	defer teardownTempRepo(t, tempDir)
+	repoPath := filepath.Join(tempDir, "platform")
+	assert.NoError(t, os.MkdirAll(filepath.Join(repoPath, ".git"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(repoPath, "services", "api"), 0755))
+
+	assert.Equal(t, "Cdaprod/platform", RepoKey(repoPath))
+	assert.Equal(t, "Cdaprod/platform", RepoKey(filepath.Join(repoPath, "services", "api")), "A service directory should share its repository's key")
+	assert.Equal(t, "Cdaprod/platform", RepoKey(filepath.Join(repoPath, "services", "..")))
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.Equal(t, RepoKey(wd), RepoKey("."), "Relative paths should be resolved")
+}
+
+func TestAddWorkflowConcurrentSameRepo(t *testing.T) {
+	tempDir := setupTempRepo(t)
+	defer teardownTempRepo(t, tempDir)
+	repoPath := filepath.Join(tempDir, "platform")
+	assert.NoError(t, os.MkdirAll(filepath.Join(repoPath, ".git"), 0755))
+
+	globalNodePropPath, err := filepath.Abs(filepath.Join("..", "..", "assets", ".empty.nodeprop.yml"))
+	assert.NoError(t, err)
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(repoPath))
+	defer os.Chdir(wd)
+
+	// The review hook runs while the repository is locked; unsynchronized state is only safe
+	// if the operations never overlap, which -race checks.
+	var inside, maxInside int
+	npManager := &NodePropManager{
+		GlobalNodePropPath: globalNodePropPath,
+		Logger:             logrus.New(),
+		ReviewChange: func(path, diff string) bool {
+			inside++
+			if inside > maxInside {
+				maxInside = inside
+			}
+			time.Sleep(10 * time.Millisecond)
+			inside--
+			return true
+		},
+	}
+
+	// The same repository spelled differently, and with services in subdirectories
+	calls := []NodePropArguments{
+		{RepoPath: repoPath, Path: "services/api", Workflow: "api", Template: "go-ci"},
+		{RepoPath: ".", Path: "services/web", Workflow: "web", Template: "node-ci"},
+	}
+	var wg sync.WaitGroup
+	for _, args := range calls {
+		wg.Add(1)
+		go func(args NodePropArguments) {
+			defer wg.Done()
+			_, err := npManager.AddWorkflowWithResult(args)
+			assert.NoError(t, err, "AddWorkflowWithResult failed")
+		}(args)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, maxInside, "Operations on the same repository should not overlap")
+	assert.FileExists(t, filepath.Join(repoPath, "services", "api", ".nodeprop.yml"))
+	assert.FileExists(t, filepath.Join(repoPath, "services", "web", ".nodeprop.yml"))
+}
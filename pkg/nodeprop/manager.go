@@ -2,6 +2,9 @@
 package nodeprop
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -9,19 +12,12 @@ import (
 	"path/filepath"
 	"time"
 
-	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 	"github.com/spf13/viper"
 	"os/signal"
 	"syscall"
 )
 
-// NodePropManager represents the manager handling node properties and workflows.
-type NodePropManager struct {
-	Logger *logrus.Logger
-}
-
 // EventType represents the type of an event (e.g., success, error, info).
 type EventType string
 
@@ -31,109 +27,468 @@ const (
 	EventTypeInfo    EventType = "info"
 )
 
-// Event represents a system event with type and message.
+// Event represents a system event with type and message. Events published by an operation
+// carry its correlation ID in Metadata under MetadataCorrelationID.
 type Event struct {
-	Type    EventType
-	Message string
+	Type     EventType         `json:"type"`
+	Message  string            `json:"message"`
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // NodePropArguments holds the arguments required for a NodeProp operation.
 type NodePropArguments struct {
-	RepoPath  string
-	Workflow  string
-	Domain    string
-	Config    string
+	RepoPath      string
+	Workflow      string
+	Domain        string
+	Config        string
+	Path          string // Subdirectory of RepoPath holding the service's .nodeprop.yml (monorepos)
+	RequireFile   string // Only add the workflow when this file exists in the repository
+	SkipIfFile    string // Skip adding the workflow when this file exists in the repository
+	Directory     string // Directory of RepoPath the workflow is written to (default .github/workflows)
+	Template      string // Named workflow template to use instead of WorkflowTemplatePath, e.g. "go-ci"
+	ContentSource string // Workflow file or https:// URL to use instead of a template
 }
 
-// NodePropFile represents the structure of a generated .nodeprop.yml file.
-type NodePropFile struct {
-	ID               string            `yaml:"id"`
-	Name             string            `yaml:"name"`
-	Address          string            `yaml:"address"`
-	Capabilities     []string          `yaml:"capabilities"`
-	Status           string            `yaml:"status"`
-	Metadata         Metadata          `yaml:"metadata"`
-	CustomProperties CustomProperties  `yaml:"custom_properties"`
+// Actions reported in a WorkflowResult.
+const (
+	WorkflowCreated   = "created"
+	WorkflowUpdated   = "updated"
+	WorkflowUnchanged = "unchanged" // the workflow already matched the template; .nodeprop.yml is still regenerated
+	WorkflowSkipped   = "skipped"   // RequireFile/SkipIfFile ruled the workflow out; nothing is written
+)
+
+// WorkflowResult describes what AddWorkflowWithResult did.
+type WorkflowResult struct {
+	Action       string // created, updated, unchanged or skipped
+	Path         string // the workflow file
+	NodePropPath string // the generated .nodeprop.yml, empty when skipped
+	Reason       string // why the workflow was skipped
 }
 
-// AddWorkflow adds a new workflow to the target repository using `index-nodeprop-workflow.yml` 
-// and generates `.nodeprop.yml` using a template from `/assets/.empty.nodeprop.yml`.
+// AddWorkflow adds a new workflow to the target repository using the configured workflow template
+// and generates `.nodeprop.yml` using the configured `.empty.nodeprop.yml` template.
 func (npm *NodePropManager) AddWorkflow(args NodePropArguments) error {
-	npm.Logger.Infof("Adding workflow '%s' to repository '%s'", args.Workflow, args.RepoPath)
+	_, err := npm.AddWorkflowWithResult(args)
+	return err
+}
 
-	// Path to the local assets folder containing the workflow and .empty.nodeprop.yml.
-	assetsDir := "./assets"
+// AddWorkflowWithResult is AddWorkflow, also reporting whether the workflow was created,
+// updated, left unchanged or skipped.
+func (npm *NodePropManager) AddWorkflowWithResult(args NodePropArguments) (result WorkflowResult, err error) {
+	err = npm.runOperation(context.Background(), Operation{Name: OperationAddWorkflow, RepoPath: args.RepoPath}, func(ctx context.Context, _ Operation) error {
+		result, err = npm.addWorkflowWithResult(ctx, args)
+		return err
+	})
+	return result, err
+}
 
-	// Read the `index-nodeprop-workflow.yml` from assets directory.
-	workflowFile := filepath.Join(assetsDir, "index-nodeprop-workflow.yml")
-	workflowContent, err := ioutil.ReadFile(workflowFile)
+// addWorkflowWithResult implements AddWorkflowWithResult.
+func (npm *NodePropManager) addWorkflowWithResult(ctx context.Context, args NodePropArguments) (result WorkflowResult, err error) {
+	npm.logger(ctx).Infof("Adding workflow '%s' to repository '%s'", args.Workflow, args.RepoPath)
+	defer func() {
+		if err != nil {
+			npm.emit(ctx, Event{Type: EventTypeError, Message: fmt.Sprintf("failed to add workflow '%s' to '%s': %v", args.Workflow, args.RepoPath, err)})
+		}
+	}()
+
+	ctx, cancel := npm.operationContext(ctx, OperationAddWorkflow)
+	defer cancel()
+
+	unlock, err := npm.LockRepo(ctx, args.RepoPath)
 	if err != nil {
-		npm.Logger.Errorf("Failed to read workflow file '%s': %v", workflowFile, err)
-		return err
+		return result, err
+	}
+	defer unlock()
+
+	if args.Path != "" && !filepath.IsLocal(args.Path) {
+		return result, fmt.Errorf("nodeprop path '%s' must be a relative path inside the repository", args.Path)
+	}
+
+	if npm.RequireSignature && npm.SigningKey == nil {
+		return result, fmt.Errorf("require_signature is set but no signing key is configured")
 	}
 
-	// Write the workflow to the target repo's `.github/workflows` directory.
-	workflowPath := filepath.Join(args.RepoPath, ".github", "workflows", fmt.Sprintf("%s.yml", args.Workflow))
-	err = os.MkdirAll(filepath.Dir(workflowPath), 0755)
+	if args.Template != "" && args.ContentSource != "" {
+		return result, fmt.Errorf("a workflow template and a content source cannot both be given")
+	}
+
+	if args, err = npm.applyOwnerProfile(args); err != nil {
+		return result, err
+	}
+
+	// Render the service's addresses up front so a broken address template fails before anything is written.
+	address, addresses, err := npm.serviceAddresses(args)
 	if err != nil {
-		npm.Logger.Errorf("Failed to create workflow directory: %v", err)
-		return err
+		return result, err
 	}
 
-	err = ioutil.WriteFile(workflowPath, workflowContent, 0644)
+	workflowPath, err := workflowFilePath(args)
 	if err != nil {
-		npm.Logger.Errorf("Failed to write workflow file: %v", err)
-		return err
+		return result, err
+	}
+	result.Path = workflowPath
+
+	reason, err := npm.workflowSkipReason(args)
+	if err != nil {
+		npm.logger(ctx).Errorf("Failed to check workflow conditions: %v", err)
+		return result, err
+	}
+	if reason != "" {
+		npm.logger(ctx).Infof("Skipping workflow '%s' for repository '%s': %s", args.Workflow, args.RepoPath, reason)
+		npm.emit(ctx, Event{Type: EventTypeInfo, Message: fmt.Sprintf("skipped workflow '%s' for '%s': %s", args.Workflow, args.RepoPath, reason)})
+		result.Action, result.Reason = WorkflowSkipped, reason
+		return result, nil
 	}
 
-	npm.Logger.Infof("Workflow '%s' added successfully to repository '%s'", args.Workflow, args.RepoPath)
+	// Validate the `.empty.nodeprop.yml` template up front so a broken asset fails before anything is written.
+	nodeProp, err := npm.loadNodePropTemplate()
+	if err != nil {
+		return result, err
+	}
 
-	// Simulate workflow execution and generating `.nodeprop.yml`.
-	npm.Logger.Info("Waiting for workflow to complete...")
-	time.Sleep(5 * time.Second) // Simulated delay.
+	// Likewise refuse to touch a repository whose existing .nodeprop.yml cannot be updated.
+	if _, err := npm.loadNodePropFiles(filepath.Join(args.RepoPath, args.Path, ".nodeprop.yml")); err != nil && !os.IsNotExist(err) {
+		return result, err
+	}
 
-	// Read the `.empty.nodeprop.yml` template from assets directory.
-	emptyNodePropFile := filepath.Join(assetsDir, ".empty.nodeprop.yml")
-	emptyNodePropContent, err := ioutil.ReadFile(emptyNodePropFile)
+	// Read the workflow template: the named one when args.Template is set, the given file or URL
+	// when args.ContentSource is, the configured one otherwise.
+	workflowFile := npm.WorkflowTemplatePath
+	var workflowContent []byte
+	if args.Template != "" {
+		workflowFile = args.Template
+		workflowContent, _, err = ReadWorkflowTemplate(args.Template, npm.WorkflowTemplateDir)
+	} else if args.ContentSource != "" {
+		workflowFile = redactWorkflowSource(args.ContentSource) // recorded in provenance and events
+		workflowContent, err = ReadWorkflowSource(ctx, args.ContentSource)
+	} else {
+		workflowContent, err = ioutil.ReadFile(workflowFile)
+	}
 	if err != nil {
-		npm.Logger.Errorf("Failed to read .empty.nodeprop.yml: %v", err)
-		return err
+		npm.logger(ctx).Errorf("Failed to read workflow file '%s': %v", workflowFile, err)
+		return result, err
 	}
+	runID, templateContent := npm.newID(), workflowContent
 
-	// Unmarshal the empty nodeprop template.
-	var nodeProp NodePropFile
-	err = yaml.Unmarshal(emptyNodePropContent, &nodeProp)
+	// Inject the permissions/concurrency boilerplate required by the workflow policy.
+	workflowContent, injected, err := ApplyWorkflowPolicy(workflowContent, npm.Workflows, filepath.Base(args.RepoPath), args.Workflow)
 	if err != nil {
-		npm.Logger.Errorf("Failed to unmarshal .empty.nodeprop.yml: %v", err)
-		return err
+		npm.logger(ctx).Errorf("Failed to apply workflow policy to '%s': %v", workflowFile, err)
+		return result, err
+	}
+	for _, block := range injected {
+		npm.logger(ctx).Infof("Injected default '%s' block into workflow '%s'", block, args.Workflow)
+	}
+	if npm.Workflows.Normalize {
+		workflowContent = NormalizeWorkflowContent(workflowContent)
+	}
+
+	// Leave an existing workflow alone when it only differs in formatting. With normalization on,
+	// one that is not normalized yet, such as one with CRLF line endings, is rewritten in its
+	// normalized form, keeping its own formatting otherwise.
+	result.Action = WorkflowCreated
+	existingWorkflow, readErr := npm.readFile(workflowPath)
+	if readErr == nil {
+		result.Action = WorkflowUpdated
+		if changes, diffErr := npm.DiffWorkflow(string(workflowContent), string(existingWorkflow)); diffErr == nil && len(changes) == 0 {
+			result.Action = WorkflowUnchanged
+			if normalized := NormalizeWorkflowContent(existingWorkflow); npm.Workflows.Normalize && !bytes.Equal(normalized, existingWorkflow) {
+				result.Action, workflowContent = WorkflowUpdated, normalized
+			}
+		}
+	}
+
+	if result.Action == WorkflowUnchanged {
+		npm.logger(ctx).Infof("Workflow '%s' in repository '%s' is already up to date", args.Workflow, args.RepoPath)
+	} else {
+		if err = npm.reviewChange(args.RepoPath, workflowPath, existingWorkflow, workflowContent); err != nil {
+			return result, err
+		}
+
+		// Write the workflow to the target repo's workflow directory.
+		err = npm.files().MkdirAll(filepath.Dir(workflowPath), 0755)
+		if err != nil {
+			npm.logger(ctx).Errorf("Failed to create workflow directory: %v", err)
+			return result, err
+		}
+
+		err = npm.files().WriteFile(workflowPath, workflowContent, 0644)
+		if err != nil {
+			npm.logger(ctx).Errorf("Failed to write workflow file: %v", err)
+			return result, err
+		}
+
+		npm.logger(ctx).Infof("Workflow '%s' %s successfully in repository '%s'", args.Workflow, result.Action, args.RepoPath)
+	}
+
+	// Simulate workflow execution and generating `.nodeprop.yml`.
+	npm.logger(ctx).Info("Waiting for workflow to complete...")
+	select {
+	case <-time.After(5 * time.Second): // Simulated delay.
+	case <-ctx.Done():
+		npm.logger(ctx).Errorf("Gave up waiting for workflow '%s': %v", args.Workflow, ctx.Err())
+		return result, ctx.Err()
 	}
 
 	// Update the nodeprop template with dynamic values.
-	nodeProp.ID = uuid.New().String()
-	nodeProp.Name = filepath.Base(args.RepoPath)
-	nodeProp.Address = fmt.Sprintf("https://github.com/Cdaprod/%s", filepath.Base(args.RepoPath))
+	nodeProp.ID = npm.newID()
+	nodeProp.Name, _ = serviceIdentity(args)
+	nodeProp.Address, nodeProp.Addresses = address, addresses
+	if nodeProp.Status == "" {
+		nodeProp.Status = StatusActive
+	}
+
+	// Record the language runtimes detected in the service's directory.
+	runtimes, err := DetectRuntimes(filepath.Join(args.RepoPath, args.Path))
+	if err != nil {
+		npm.logger(ctx).Warnf("Failed to detect runtimes: %v", err)
+	}
+	nodeProp.Metadata.Runtime = runtimes
+
+	// Describe the repository's existing workflows, flagging the ones added by nodeprop.
+	nodePropPath := filepath.Join(args.RepoPath, args.Path, ".nodeprop.yml")
+	workflowDir, _ := workflowDirectory(args) // validated with the workflow path above
+	workflows, err := discoverWorkflows(npm.repoFS(args.RepoPath), workflowDir, RepoAddress(args.RepoPath))
+	if err != nil {
+		npm.logger(ctx).Warnf("Failed to parse some workflows: %v", err)
+	}
+	managed := npm.managedWorkflowFiles(nodePropPath)
+	if rel, relErr := filepath.Rel(args.RepoPath, workflowPath); relErr == nil {
+		managed[filepath.ToSlash(rel)] = true
+	}
+	for i := range workflows {
+		workflows[i].Managed = managed[workflows[i].File]
+	}
+	nodeProp.Metadata.Workflows = workflows
+
+	// Add the capabilities implied by what the workflows do.
+	inferred, err := inferCapabilities(npm.repoFS(args.RepoPath), workflowDir, npm.capabilityKeywords())
+	if err != nil {
+		npm.logger(ctx).Warnf("Failed to infer capabilities from workflows: %v", err)
+	}
+	nodeProp.Capabilities = mergeCapabilities(nodeProp.Capabilities, inferred)
 	nodeProp.Metadata.LastUpdated = time.Now().Format(time.RFC3339)
+	nodeProp.Metadata.GeneratedBy = npm.provenance(runID, workflowFile, templateContent)
 	nodeProp.CustomProperties.Domain = args.Domain
 
+	// Sign the document when a signing key is configured, inline or in a detached .sig file.
+	// Any signature carried over from the previous document no longer applies.
+	nodeProp.Metadata.Signature = ""
+	var detachedSignature string
+	if npm.SigningKey != nil {
+		signature, err := SignNodeProp(nodeProp, npm.SigningKey)
+		if err != nil {
+			npm.logger(ctx).Errorf("Failed to sign .nodeprop.yml: %v", err)
+			return result, err
+		}
+		if npm.SignDetached {
+			detachedSignature = signature
+		} else {
+			nodeProp.Metadata.Signature = signature
+		}
+	}
+
 	// Marshal the updated .nodeprop.yml file.
 	nodePropYAML, err := yaml.Marshal(&nodeProp)
 	if err != nil {
-		npm.Logger.Errorf("Failed to marshal .nodeprop.yml: %v", err)
-		return err
+		npm.logger(ctx).Errorf("Failed to marshal .nodeprop.yml: %v", err)
+		return result, err
 	}
 
-	// Write the updated .nodeprop.yml to the target repository.
-	nodePropPath := filepath.Join(args.RepoPath, ".nodeprop.yml")
-	err = ioutil.WriteFile(nodePropPath, nodePropYAML, 0644)
+	// Write the updated .nodeprop.yml to the target repository (or its service subdirectory).
+	err = npm.files().MkdirAll(filepath.Dir(nodePropPath), 0755)
+	if err != nil {
+		npm.logger(ctx).Errorf("Failed to create nodeprop directory: %v", err)
+		return result, err
+	}
+
+	// Replace only the first document of an existing multi-document .nodeprop.yml, preserving the rest.
+	existingNodeProp, readErr := npm.readFile(nodePropPath)
+	if readErr == nil {
+		nodePropYAML, err = ReplaceNodePropDocument(existingNodeProp, 0, nodeProp)
+		if err != nil {
+			npm.logger(ctx).Errorf("Refusing to overwrite %s: %v", nodePropPath, err)
+			return result, fmt.Errorf("failed to update '%s': %w", nodePropPath, err)
+		}
+	} else if !os.IsNotExist(readErr) {
+		return result, readErr
+	}
+
+	if detachedSignature != "" {
+		if documents := splitYAMLDocuments(nodePropYAML); len(documents) > 1 {
+			return result, fmt.Errorf("%s has %d nodeprop documents; a detached signature can only cover one", nodePropPath, len(documents))
+		}
+	}
+
+	if err = npm.reviewChange(args.RepoPath, nodePropPath, existingNodeProp, nodePropYAML); err != nil {
+		return result, err
+	}
+
+	err = npm.files().WriteFile(nodePropPath, nodePropYAML, 0644)
+	if err != nil {
+		npm.logger(ctx).Errorf("Failed to write .nodeprop.yml: %v", err)
+		return result, err
+	}
+
+	// Replace the detached signature, or remove one left over from an earlier run so it does
+	// not shadow the new document.
+	signaturePath := nodePropPath + signatureFileSuffix
+	if detachedSignature != "" {
+		err = npm.files().WriteFile(signaturePath, []byte(detachedSignature+"\n"), 0644)
+	} else if _, statErr := npm.statFile(signaturePath); statErr == nil {
+		err = npm.files().Remove(signaturePath)
+	}
+	if err != nil {
+		npm.logger(ctx).Errorf("Failed to update detached signature: %v", err)
+		return result, err
+	}
+
+	npm.logger(ctx).Infof(".nodeprop.yml generated successfully at %s (run %s)", nodePropPath, runID)
+	result.NodePropPath = nodePropPath
+	npm.updateCatalog(ctx, nodeProp, false)
+	npm.emit(ctx, Event{Type: EventTypeSuccess, Message: fmt.Sprintf("%s workflow '%s' from %s and generated %s", result.Action, args.Workflow, workflowFile, nodePropPath)})
+	return result, nil
+}
+
+// ErrNodePropNotFound is returned when there is no .nodeprop.yml to delete.
+var ErrNodePropNotFound = errors.New("no .nodeprop.yml found")
+
+// DeleteNodeProp removes the `.nodeprop.yml` from repoPath (a repository, or the service
+// subdirectory of a monorepo) together with its detached signature, if any.
+func (npm *NodePropManager) DeleteNodeProp(ctx context.Context, repoPath string) error {
+	return npm.runOperation(ctx, Operation{Name: OperationDeleteNodeProp, RepoPath: repoPath}, func(ctx context.Context, op Operation) error {
+		return npm.deleteNodeProp(ctx, op.RepoPath)
+	})
+}
+
+// deleteNodeProp implements DeleteNodeProp.
+func (npm *NodePropManager) deleteNodeProp(ctx context.Context, repoPath string) error {
+	ctx, cancel := npm.operationContext(ctx, OperationDeleteNodeProp)
+	defer cancel()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	unlock, err := npm.LockRepo(ctx, repoPath)
 	if err != nil {
-		npm.Logger.Errorf("Failed to write .nodeprop.yml: %v", err)
 		return err
 	}
+	defer unlock()
 
-	npm.Logger.Infof(".nodeprop.yml generated successfully at %s", nodePropPath)
+	nodePropPath := filepath.Join(repoPath, ".nodeprop.yml")
+	if _, err := npm.statFile(nodePropPath); os.IsNotExist(err) {
+		return fmt.Errorf("%w in '%s', nothing to delete", ErrNodePropNotFound, repoPath)
+	}
+	documents, _ := npm.loadNodePropFiles(nodePropPath)
+	if err := npm.files().Remove(nodePropPath); err != nil {
+		npm.logger(ctx).Errorf("Failed to delete %s: %v", nodePropPath, err)
+		return err
+	}
+
+	if _, err := npm.statFile(nodePropPath + signatureFileSuffix); err == nil {
+		if err := npm.files().Remove(nodePropPath + signatureFileSuffix); err != nil {
+			npm.logger(ctx).Errorf("Failed to delete detached signature of %s: %v", nodePropPath, err)
+			return err
+		}
+	}
+
+	npm.logger(ctx).Infof(".nodeprop.yml deleted from %s", repoPath)
+	for _, document := range documents {
+		npm.updateCatalog(ctx, document, true)
+	}
+	npm.emit(ctx, Event{Type: EventTypeSuccess, Message: fmt.Sprintf("deleted %s", nodePropPath)})
 	return nil
 }
 
+// workflowDirectory returns the directory of the repository workflows are written to and
+// discovered in: args.Directory, or `.github/workflows` when it is empty.
+func workflowDirectory(args NodePropArguments) (string, error) {
+	if args.Directory == "" {
+		return filepath.Join(".github", "workflows"), nil
+	}
+	if !filepath.IsLocal(args.Directory) {
+		return "", fmt.Errorf("workflow directory '%s' must be a relative path inside the repository", args.Directory)
+	}
+	return filepath.Clean(args.Directory), nil
+}
+
+// workflowFilePath returns where the workflow is written: args.Directory (default
+// `.github/workflows`) inside the repository, with a `.yml` extension unless the workflow
+// name already ends in `.yml` or `.yaml`.
+func workflowFilePath(args NodePropArguments) (string, error) {
+	directory, err := workflowDirectory(args)
+	if err != nil {
+		return "", err
+	}
+
+	fileName := args.Workflow
+	if ext := filepath.Ext(fileName); ext != ".yml" && ext != ".yaml" {
+		fileName += ".yml"
+	}
+	return filepath.Join(args.RepoPath, directory, fileName), nil
+}
+
+// workflowSkipReason checks the RequireFile/SkipIfFile conditions against readFS and returns
+// why the workflow should be skipped, or an empty string when it should be added.
+func (npm *NodePropManager) workflowSkipReason(args NodePropArguments) (string, error) {
+	if args.RequireFile != "" {
+		exists, err := checkFile(npm.statFile, args.RepoPath, args.RequireFile)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return fmt.Sprintf("required file '%s' not found", args.RequireFile), nil
+		}
+	}
+	if args.SkipIfFile != "" {
+		exists, err := checkFile(npm.statFile, args.RepoPath, args.SkipIfFile)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return fmt.Sprintf("file '%s' already exists", args.SkipIfFile), nil
+		}
+	}
+	return "", nil
+}
+
+// managedWorkflowFiles returns the workflow files an existing .nodeprop.yml marks as managed by
+// nodeprop, so regenerating it keeps workflows added in earlier runs flagged.
+func (npm *NodePropManager) managedWorkflowFiles(nodePropPath string) map[string]bool {
+	managed := map[string]bool{}
+	nodeProps, err := npm.loadNodePropFiles(nodePropPath)
+	if err != nil || len(nodeProps) == 0 {
+		return managed
+	}
+	for _, workflow := range nodeProps[0].Metadata.Workflows {
+		if workflow.Managed {
+			managed[workflow.File] = true
+		}
+	}
+	return managed
+}
+
+// RepoAddress returns the GitHub URL of the repository: the one its GitHub remote points to,
+// or the repository of the same name under defaultOwner when it has none.
+func RepoAddress(repoPath string) string {
+	if owner, repo, ok := githubRemote(repoPath); ok {
+		return fmt.Sprintf("https://github.com/%s/%s", owner, repo)
+	}
+	return fmt.Sprintf("https://github.com/%s/%s", defaultOwner, filepath.Base(repoPath))
+}
+
+// serviceIdentity returns the nodeprop name and address for the repository, or for the
+// service in args.Path when the repository is a monorepo.
+func serviceIdentity(args NodePropArguments) (string, string) {
+	repo := filepath.Base(args.RepoPath)
+	address := RepoAddress(args.RepoPath)
+	if args.Path == "" {
+		return repo, address
+	}
+
+	subPath := filepath.ToSlash(filepath.Clean(args.Path))
+	return filepath.Base(subPath), fmt.Sprintf("%s/tree/HEAD/%s", address, subPath)
+}
+
 // SignalHandler listens for OS signals to handle reloads or shutdowns.
 func (npm *NodePropManager) SignalHandler() {
 	signalCh := make(chan os.Signal, 1)
@@ -154,7 +509,15 @@ func (npm *NodePropManager) SignalHandler() {
 
 // ReloadConfig reloads the configuration using Viper.
 func (npm *NodePropManager) ReloadConfig(args NodePropArguments) error {
+	return npm.runOperation(context.Background(), Operation{Name: OperationReloadConfig}, func(context.Context, Operation) error {
+		return npm.reloadConfig(args)
+	})
+}
+
+// reloadConfig implements ReloadConfig.
+func (npm *NodePropManager) reloadConfig(args NodePropArguments) error {
 	viper.SetConfigFile(args.Config) // Use the specified config file.
+	BindEnvironment()
 	err := viper.ReadInConfig()
 	if err != nil {
 		npm.Logger.Errorf("Error reading config file during reload: %v", err)
@@ -2,15 +2,17 @@
 package nodeprop
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
-	"time"
 
-	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
 )
@@ -24,6 +26,16 @@ func setupTempRepo(t *testing.T) string {
 	return tempDir
 }
 
+// setupMemRepo returns an in-memory filesystem holding an empty repository, and its path
+func setupMemRepo(t *testing.T) (*MemFS, string) {
+	memFS := &MemFS{}
+	repoPath := "/repos/nodeprop_test_repo"
+	if err := memFS.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatalf("Failed to create in-memory repository: %v", err)
+	}
+	return memFS, repoPath
+}
+
 // Helper function to clean up the temporary directory after testing
 func teardownTempRepo(t *testing.T, dir string) {
 	err := os.RemoveAll(dir)
@@ -118,7 +130,10 @@ jobs:
 
 	// Initialize NodePropManager
 	npManager := &NodePropManager{
-		Logger: logger,
+		GlobalNodePropPath:   filepath.Join(assetsDir, ".empty.nodeprop.yml"),
+		WorkflowTemplatePath: filepath.Join(assetsDir, "index-nodeprop-workflow.yml"),
+		IDs:                  fixedIDGenerator("test-id"),
+		Logger:               logger,
 	}
 
 	// Define NodePropArguments
@@ -159,11 +174,178 @@ workflow_template_path: "./assets/index-nodeprop-workflow.yml"
 	err = yaml.Unmarshal(nodePropContent, &nodeProp)
 	assert.NoError(t, err, "Failed to unmarshal .nodeprop.yml")
 
-	assert.NotEmpty(t, nodeProp.ID, "NodeProp ID should not be empty")
+	assert.Equal(t, "test-id", nodeProp.ID, "NodeProp ID mismatch")
 	assert.Equal(t, filepath.Base(repoPath), nodeProp.Name, "NodeProp Name mismatch")
 	assert.Equal(t, fmt.Sprintf("https://github.com/Cdaprod/%s", filepath.Base(repoPath)), nodeProp.Address, "NodeProp Address mismatch")
-	assert.Equal(t, "active", nodeProp.Status, "NodeProp Status should be active")
+	assert.Equal(t, "active", nodeProp.Status, "An empty template status should default to active")
 	assert.Equal(t, "test.domain", nodeProp.CustomProperties.Domain, "NodeProp Domain mismatch")
+	assert.Equal(t, "test-id", nodeProp.Metadata.GeneratedBy.RunID, "Provenance run ID mismatch")
+	assert.Equal(t, SourceAPI, nodeProp.Metadata.GeneratedBy.Source, "Provenance source mismatch")
+	assert.Equal(t, npManager.WorkflowTemplatePath, nodeProp.Metadata.GeneratedBy.Template, "Provenance template mismatch")
+	assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(indexWorkflowTemplate))), nodeProp.Metadata.GeneratedBy.TemplateHash, "Provenance template hash mismatch")
+	assert.Equal(t, []Workflow{{
+		Name:     "TestWorkflow",
+		File:     ".github/workflows/test-workflow.yml",
+		Triggers: []string{"push"},
+		Jobs:     []string{"build"},
+		Badge:    fmt.Sprintf("https://github.com/Cdaprod/%s/actions/workflows/test-workflow.yml/badge.svg", filepath.Base(repoPath)),
+		Managed:  true,
+	}}, nodeProp.Metadata.Workflows, "NodeProp workflows mismatch")
+}
+
+func TestAddWorkflowMalformedTemplate(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+
+	// Setup temporary repository
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	// Create a .empty.nodeprop.yml whose github.stars on line 5 is not an int
+	malformedTemplate := "id: \"\"\nname: \"\"\nmetadata:\n  github:\n    stars: many\n"
+	templatePath := filepath.Join(repoPath, ".empty.nodeprop.yml")
+	err := ioutil.WriteFile(templatePath, []byte(malformedTemplate), 0644)
+	assert.NoError(t, err, "Failed to write malformed .empty.nodeprop.yml")
+
+	npManager := &NodePropManager{
+		GlobalNodePropPath:   templatePath,
+		WorkflowTemplatePath: filepath.Join(repoPath, "index-nodeprop-workflow.yml"),
+		Logger:               logger,
+	}
+
+	err = npManager.AddWorkflow(NodePropArguments{
+		RepoPath: repoPath,
+		Workflow: "test-workflow",
+	})
+	assert.Error(t, err, "AddWorkflow should fail on a malformed template")
+
+	templateErr, ok := err.(*TemplateError)
+	assert.True(t, ok, "Expected a *TemplateError, got %T", err)
+	if ok {
+		assert.Equal(t, templatePath, templateErr.Path, "TemplateError should name the template file")
+		assert.Equal(t, 5, templateErr.Line, "TemplateError should name the offending line")
+	}
+	assert.Contains(t, err.Error(), templatePath, "Error message should name the template file")
+	assert.Contains(t, err.Error(), "line 5", "Error message should name the offending line")
+
+	// Nothing should be written when the template is broken
+	_, err = os.Stat(filepath.Join(repoPath, ".github", "workflows", "test-workflow.yml"))
+	assert.True(t, os.IsNotExist(err), "Workflow file should not be created")
+
+	// With fallback enabled the embedded default is used instead
+	npManager.TemplateFallback = true
+	nodeProp, err := npManager.loadNodePropTemplate()
+	assert.NoError(t, err, "Embedded template should be used as a fallback")
+	assert.Empty(t, nodeProp.ID, "Embedded template ID should be empty")
+}
+
+func TestAddWorkflowConditions(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+
+	// Setup temporary repository containing a go.mod
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	err := ioutil.WriteFile(filepath.Join(repoPath, "go.mod"), []byte("module example.com/test\n"), 0644)
+	assert.NoError(t, err, "Failed to write go.mod")
+
+	npManager := &NodePropManager{
+		Logger: logger,
+	}
+
+	// Proceed: the required file exists and the skip file does not
+	reason, err := npManager.workflowSkipReason(NodePropArguments{
+		RepoPath:    repoPath,
+		RequireFile: "go.mod",
+		SkipIfFile:  ".github/workflows/go-ci.yml",
+	})
+	assert.NoError(t, err, "workflowSkipReason failed")
+	assert.Empty(t, reason, "Workflow should proceed when go.mod exists")
+
+	// Skip: the required file is missing
+	args := NodePropArguments{
+		RepoPath:    repoPath,
+		Workflow:    "node-ci",
+		RequireFile: "package.json",
+	}
+	reason, err = npManager.workflowSkipReason(args)
+	assert.NoError(t, err, "workflowSkipReason failed")
+	assert.Equal(t, "required file 'package.json' not found", reason, "Skip reason mismatch")
+
+	err = npManager.AddWorkflow(args)
+	assert.NoError(t, err, "Skipping a workflow should not be an error")
+	_, err = os.Stat(filepath.Join(repoPath, ".github", "workflows", "node-ci.yml"))
+	assert.True(t, os.IsNotExist(err), "Skipped workflow should not be created")
+
+	// Skip: the skip file exists
+	args = NodePropArguments{
+		RepoPath:   repoPath,
+		Workflow:   "go-ci",
+		SkipIfFile: "go.mod",
+	}
+	reason, err = npManager.workflowSkipReason(args)
+	assert.NoError(t, err, "workflowSkipReason failed")
+	assert.Equal(t, "file 'go.mod' already exists", reason, "Skip reason mismatch")
+
+	err = npManager.AddWorkflow(args)
+	assert.NoError(t, err, "Skipping a workflow should not be an error")
+	_, err = os.Stat(filepath.Join(repoPath, ".github", "workflows", "go-ci.yml"))
+	assert.True(t, os.IsNotExist(err), "Skipped workflow should not be created")
+
+	// Paths outside the repository are refused
+	for _, path := range []string{"../../etc/passwd", "/etc/passwd"} {
+		_, err = npManager.workflowSkipReason(NodePropArguments{RepoPath: repoPath, RequireFile: path})
+		assert.Error(t, err, "RequireFile %s should be refused", path)
+		_, err = npManager.workflowSkipReason(NodePropArguments{RepoPath: repoPath, SkipIfFile: path})
+		assert.Error(t, err, "SkipIfFile %s should be refused", path)
+	}
+}
+
+func TestWorkflowFilePath(t *testing.T) {
+	repoPath := filepath.Join("src", "nodeprop")
+
+	path, err := workflowFilePath(NodePropArguments{RepoPath: repoPath, Workflow: "ci"})
+	assert.NoError(t, err, "workflowFilePath failed")
+	assert.Equal(t, filepath.Join(repoPath, ".github", "workflows", "ci.yml"), path, "Default workflow path mismatch")
+
+	path, err = workflowFilePath(NodePropArguments{RepoPath: repoPath, Workflow: "action", Directory: ".github/actions/setup"})
+	assert.NoError(t, err, "workflowFilePath failed")
+	assert.Equal(t, filepath.Join(repoPath, ".github", "actions", "setup", "action.yml"), path, "Custom directory workflow path mismatch")
+
+	path, err = workflowFilePath(NodePropArguments{RepoPath: repoPath, Workflow: "release.yaml", Directory: "ci"})
+	assert.NoError(t, err, "workflowFilePath failed")
+	assert.Equal(t, filepath.Join(repoPath, "ci", "release.yaml"), path, "Existing extension should be kept")
+
+	_, err = workflowFilePath(NodePropArguments{RepoPath: repoPath, Workflow: "ci", Directory: "../outside"})
+	assert.Error(t, err, "Directories outside the repository should be rejected")
+}
+
+func TestDeleteNodeProp(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	npManager := &NodePropManager{
+		Logger: logrus.New(),
+	}
+
+	nodePropPath := filepath.Join(repoPath, ".nodeprop.yml")
+	err := ioutil.WriteFile(nodePropPath, []byte("id: test\n"), 0644)
+	assert.NoError(t, err, "Failed to write .nodeprop.yml")
+	err = ioutil.WriteFile(nodePropPath+".sig", []byte("signature\n"), 0644)
+	assert.NoError(t, err, "Failed to write .nodeprop.yml.sig")
+
+	err = npManager.DeleteNodeProp(context.Background(), repoPath)
+	assert.NoError(t, err, "DeleteNodeProp failed")
+	_, err = os.Stat(nodePropPath)
+	assert.True(t, os.IsNotExist(err), ".nodeprop.yml should be deleted")
+	_, err = os.Stat(nodePropPath + ".sig")
+	assert.True(t, os.IsNotExist(err), "Detached signature should be deleted")
+
+	// Deleting again reports that there is nothing to delete
+	err = npManager.DeleteNodeProp(context.Background(), repoPath)
+	assert.ErrorIs(t, err, ErrNodePropNotFound, "Expected ErrNodePropNotFound for a missing .nodeprop.yml")
+	assert.ErrorContains(t, err, "nothing to delete", "Error should explain that there is nothing to delete")
 }
 
 func TestReloadConfig(t *testing.T) {
@@ -210,4 +392,242 @@ workflow_template_path: "./assets/new_workflow_template.yml"
 	// Verify the new configuration is loaded
 	workflowTemplatePath := viper.GetString("workflow_template_path")
 	assert.Equal(t, "./assets/new_workflow_template.yml", workflowTemplatePath, "Config reload did not update workflow_template_path correctly")
-}
\ No newline at end of file
+}
+func TestReloadConfigEnvironment(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	configPath := filepath.Join(repoPath, "config.yaml")
+	config := `
+global_nodeprop_path: "./assets/.empty.nodeprop.yml"
+workflow_template_path: "./assets/index-nodeprop-workflow.yml"
+`
+	err := ioutil.WriteFile(configPath, []byte(config), 0644)
+	assert.NoError(t, err, "Failed to write config.yaml")
+
+	// Environment variables override the file and set keys it does not mention
+	t.Setenv("NODEPROP_WORKFLOW_TEMPLATE_PATH", "/etc/nodeprop/workflow.yml")
+	t.Setenv("NODEPROP_WORKFLOWS_ENFORCE_CONCURRENCY", "true")
+
+	npManager := &NodePropManager{
+		Logger: logrus.New(),
+	}
+	err = npManager.ReloadConfig(NodePropArguments{Config: configPath})
+	assert.NoError(t, err, "ReloadConfig failed")
+
+	assert.Equal(t, "/etc/nodeprop/workflow.yml", viper.GetString("workflow_template_path"), "Environment should override the config file")
+	assert.Equal(t, "./assets/.empty.nodeprop.yml", viper.GetString("global_nodeprop_path"), "Keys without an environment variable should come from the file")
+	assert.True(t, viper.GetBool("workflows.enforce_concurrency"), "Nested keys should be settable from the environment")
+}
+
+func TestAddWorkflowWithResult(t *testing.T) {
+	memFS, repoPath := setupMemRepo(t)
+
+	npManager := &NodePropManager{
+		GlobalNodePropPath:   filepath.Join("..", "..", "assets", ".empty.nodeprop.yml"),
+		WorkflowTemplatePath: filepath.Join("..", "..", "assets", "default_workflow", "index-nodeprop-workflow.yml"),
+		Files:                memFS,
+		Logger:               logrus.New(),
+	}
+	args := NodePropArguments{RepoPath: repoPath, Workflow: "nodeprop"}
+	workflowPath := filepath.Join(repoPath, ".github", "workflows", "nodeprop.yml")
+
+	result, err := npManager.AddWorkflowWithResult(args)
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+	assert.Equal(t, WorkflowResult{
+		Action:       WorkflowCreated,
+		Path:         workflowPath,
+		NodePropPath: filepath.Join(repoPath, ".nodeprop.yml"),
+	}, result, "Result of creating the workflow mismatch")
+
+	// A semantically different workflow in the repository is updated
+	err = memFS.WriteFile(workflowPath, []byte("name: Edited\non: push\njobs: {}\n"), 0644)
+	assert.NoError(t, err, "Failed to edit workflow")
+	result, err = npManager.AddWorkflowWithResult(args)
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+	assert.Equal(t, WorkflowUpdated, result.Action, "Editing the workflow should report an update")
+
+	// Unmet conditions skip the workflow
+	args.RequireFile = "go.mod"
+	result, err = npManager.AddWorkflowWithResult(args)
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+	assert.Equal(t, WorkflowResult{
+		Action: WorkflowSkipped,
+		Path:   workflowPath,
+		Reason: "required file 'go.mod' not found",
+	}, result, "Result of skipping the workflow mismatch")
+}
+
+func TestAddWorkflowNormalizesContent(t *testing.T) {
+	memFS, repoPath := setupMemRepo(t)
+	templateDir := setupTempRepo(t)
+	defer teardownTempRepo(t, templateDir)
+
+	err := ioutil.WriteFile(filepath.Join(templateDir, "crlf.yml"), []byte("name: CRLF \r\non: push\r\njobs:\r\n  build:\t\r\n    runs-on: ubuntu-latest"), 0644)
+	assert.NoError(t, err, "Failed to write workflow template")
+
+	npManager := &NodePropManager{
+		GlobalNodePropPath:  filepath.Join("..", "..", "assets", ".empty.nodeprop.yml"),
+		WorkflowTemplateDir: templateDir,
+		Workflows:           WorkflowPolicy{Normalize: true},
+		Files:               memFS,
+		Logger:              logrus.New(),
+	}
+	result, err := npManager.AddWorkflowWithResult(NodePropArguments{RepoPath: repoPath, Workflow: "ci", Template: "crlf"})
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+
+	content, err := npManager.readFile(result.Path)
+	assert.NoError(t, err, "Failed to read workflow")
+	assert.Equal(t, "name: CRLF\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n", string(content), "Written workflow should be normalized")
+}
+
+func TestAddWorkflowNormalizesExistingWorkflow(t *testing.T) {
+	memFS, repoPath := setupMemRepo(t)
+	templateDir := setupTempRepo(t)
+	defer teardownTempRepo(t, templateDir)
+
+	err := ioutil.WriteFile(filepath.Join(templateDir, "ci.yml"), []byte("name: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n"), 0644)
+	assert.NoError(t, err, "Failed to write workflow template")
+	workflowPath := filepath.Join(repoPath, ".github", "workflows", "ci.yml")
+	assert.NoError(t, memFS.MkdirAll(filepath.Dir(workflowPath), 0755))
+	assert.NoError(t, memFS.WriteFile(workflowPath, []byte("name: CI\r\non: push  \r\njobs:\r\n  build: {runs-on: ubuntu-latest}\r\n"), 0644))
+
+	npManager := &NodePropManager{
+		GlobalNodePropPath:  filepath.Join("..", "..", "assets", ".empty.nodeprop.yml"),
+		WorkflowTemplateDir: templateDir,
+		Files:               memFS,
+		Logger:              logrus.New(),
+	}
+	args := NodePropArguments{RepoPath: repoPath, Workflow: "ci", Template: "ci"}
+
+	// Without normalization a workflow differing only in formatting is left alone
+	result, err := npManager.AddWorkflowWithResult(args)
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+	assert.Equal(t, WorkflowUnchanged, result.Action)
+
+	// With it, the CRLF workflow is normalized, keeping its own formatting
+	npManager.Workflows.Normalize = true
+	result, err = npManager.AddWorkflowWithResult(args)
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+	assert.Equal(t, WorkflowUpdated, result.Action, "An unnormalized workflow should be rewritten")
+	content, err := npManager.readFile(workflowPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "name: CI\non: push\njobs:\n  build: {runs-on: ubuntu-latest}\n", string(content))
+
+	result, err = npManager.AddWorkflowWithResult(args)
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+	assert.Equal(t, WorkflowUnchanged, result.Action, "A normalized workflow should be left alone")
+}
+
+func TestAddWorkflowReviewChange(t *testing.T) {
+	memFS, repoPath := setupMemRepo(t)
+
+	workflowPath := filepath.Join(repoPath, ".github", "workflows", "ci.yml")
+	assert.NoError(t, memFS.MkdirAll(filepath.Dir(workflowPath), 0755))
+	assert.NoError(t, memFS.WriteFile(workflowPath, []byte("name: Old\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n"), 0644))
+
+	var reviewed []string
+	npManager := &NodePropManager{
+		GlobalNodePropPath: filepath.Join("..", "..", "assets", ".empty.nodeprop.yml"),
+		Files:              memFS,
+		Logger:             logrus.New(),
+		ReviewChange: func(path, diff string) bool {
+			reviewed = append(reviewed, diff)
+			return false
+		},
+	}
+	_, err := npManager.AddWorkflowWithResult(NodePropArguments{RepoPath: repoPath, Workflow: "ci", Template: "go-ci"})
+	assert.ErrorIs(t, err, ErrChangeDeclined, "A declined change should abort the operation")
+
+	if assert.Len(t, reviewed, 1, "Only the workflow should have been reviewed") {
+		assert.Contains(t, reviewed[0], "--- a/.github/workflows/ci.yml\n+++ b/.github/workflows/ci.yml\n")
+		assert.Contains(t, reviewed[0], "\n-name: Old\n")
+	}
+	content, err := npManager.readFile(workflowPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "name: Old", "A declined workflow should not be written")
+	_, err = npManager.statFile(filepath.Join(repoPath, ".nodeprop.yml"))
+	assert.True(t, os.IsNotExist(err), ".nodeprop.yml should not be written")
+}
+
+func TestAddWorkflowKeepsUnparseableNodeProp(t *testing.T) {
+	memFS, repoPath := setupMemRepo(t)
+
+	broken := []byte("id: \"api\"\nmetadata:\n  github:\n    stars: many\n")
+	nodePropPath := filepath.Join(repoPath, ".nodeprop.yml")
+	assert.NoError(t, memFS.WriteFile(nodePropPath, broken, 0644))
+
+	npManager := &NodePropManager{
+		GlobalNodePropPath: filepath.Join("..", "..", "assets", ".empty.nodeprop.yml"),
+		Files:              memFS,
+		Logger:             logrus.New(),
+	}
+	_, err := npManager.AddWorkflowWithResult(NodePropArguments{RepoPath: repoPath, Workflow: "ci", Template: "go-ci"})
+	assert.ErrorContains(t, err, "document 1", "An unparseable .nodeprop.yml should fail the operation")
+
+	content, err := npManager.readFile(nodePropPath)
+	assert.NoError(t, err)
+	assert.Equal(t, broken, content, "The existing .nodeprop.yml should be left alone")
+	_, err = npManager.statFile(filepath.Join(repoPath, ".github", "workflows", "ci.yml"))
+	assert.True(t, os.IsNotExist(err), "Nothing should be written")
+}
+
+func TestAddWorkflowDetachedSignature(t *testing.T) {
+	memFS, repoPath := setupMemRepo(t)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err, "Failed to generate key")
+
+	npManager := &NodePropManager{
+		GlobalNodePropPath: filepath.Join("..", "..", "assets", ".empty.nodeprop.yml"),
+		SigningKey:         privateKey,
+		SignDetached:       true,
+		Files:              memFS,
+		Logger:             logrus.New(),
+	}
+	args := NodePropArguments{RepoPath: repoPath, Workflow: "ci", Template: "go-ci"}
+	nodePropPath := filepath.Join(repoPath, ".nodeprop.yml")
+
+	// A detached signature is written next to the unsigned document
+	_, err = npManager.AddWorkflowWithResult(args)
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+	_, err = npManager.statFile(nodePropPath + ".sig")
+	assert.NoError(t, err, "Detached signature should be written")
+	nodeProps, err := npManager.loadNodePropFiles(nodePropPath)
+	assert.NoError(t, err, "Failed to read .nodeprop.yml")
+	assert.Empty(t, nodeProps[0].Metadata.Signature, "Detached signing should not sign inline")
+	assert.NoError(t, verifyNodePropFile(npManager.readFile, nodePropPath, []ed25519.PublicKey{publicKey}), "Detached signature should verify")
+
+	// Switching to inline signing removes the stale detached signature
+	npManager.SignDetached = false
+	_, err = npManager.AddWorkflowWithResult(args)
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+	_, err = npManager.statFile(nodePropPath + ".sig")
+	assert.True(t, os.IsNotExist(err), "Stale detached signature should be removed")
+	nodeProps, err = npManager.loadNodePropFiles(nodePropPath)
+	assert.NoError(t, err, "Failed to read .nodeprop.yml")
+	assert.NotEmpty(t, nodeProps[0].Metadata.Signature, "Document should be signed inline")
+	assert.NoError(t, verifyNodePropFile(npManager.readFile, nodePropPath, []ed25519.PublicKey{publicKey}), "Inline signature should verify")
+}
+
+func TestAddWorkflowCustomDirectory(t *testing.T) {
+	memFS, repoPath := setupMemRepo(t)
+
+	npManager := &NodePropManager{
+		GlobalNodePropPath: filepath.Join("..", "..", "assets", ".empty.nodeprop.yml"),
+		Files:              memFS,
+		Logger:             logrus.New(),
+	}
+	result, err := npManager.AddWorkflowWithResult(NodePropArguments{RepoPath: repoPath, Workflow: "image", Template: "docker", Directory: "ci/workflows"})
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+	assert.Equal(t, filepath.Join(repoPath, "ci", "workflows", "image.yml"), result.Path)
+
+	// Workflows and capabilities come from the directory the workflow was written to
+	nodeProps, err := npManager.loadNodePropFiles(result.NodePropPath)
+	assert.NoError(t, err, "Failed to read .nodeprop.yml")
+	if assert.Len(t, nodeProps[0].Metadata.Workflows, 1, "The written workflow should be discovered") {
+		assert.Equal(t, "ci/workflows/image.yml", nodeProps[0].Metadata.Workflows[0].File)
+		assert.True(t, nodeProps[0].Metadata.Workflows[0].Managed, "The written workflow should be managed")
+	}
+	assert.Contains(t, nodeProps[0].Capabilities, "docker", "Capabilities should be inferred from the written workflow")
+}
@@ -0,0 +1,110 @@
+// pkg/nodeprop/middleware.go
+package nodeprop
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OperationReloadConfig names ReloadConfig, which only reads the config file and is not bounded
+// by a timeout.
+const OperationReloadConfig = "reload_config"
+
+// Operation identifies a call of one of the manager's public methods.
+type Operation struct {
+	Name     string // OperationAddWorkflow, OperationDeleteNodeProp, ...
+	RepoPath string // the repository operated on; empty for ReloadConfig
+}
+
+// OpFunc runs an operation.
+type OpFunc func(ctx context.Context, op Operation) error
+
+// OperationMiddleware wraps every operation of the manager, e.g. to log, measure, retry or
+// refuse it. It may return without calling next.
+type OperationMiddleware func(next OpFunc) OpFunc
+
+// WithOperationMiddleware adds middleware around every operation of the manager, inside the
+// middleware added before it. It is meant to be called while setting the manager up, before
+// any operation runs.
+func (npm *NodePropManager) WithOperationMiddleware(middleware ...OperationMiddleware) *NodePropManager {
+	npm.Middleware = append(npm.Middleware, middleware...)
+	return npm
+}
+
+// runOperation runs fn through the manager's middleware chain, the first middleware outermost,
+// with a context carrying the correlation ID of the operation.
+func (npm *NodePropManager) runOperation(ctx context.Context, op Operation, fn OpFunc) error {
+	for i := len(npm.Middleware) - 1; i >= 0; i-- {
+		fn = npm.Middleware[i](fn)
+	}
+	return fn(npm.withCorrelationID(ctx), op)
+}
+
+// LoggingMiddleware logs the outcome, duration and correlation ID of every operation.
+func LoggingMiddleware(logger *logrus.Logger) OperationMiddleware {
+	return func(next OpFunc) OpFunc {
+		return func(ctx context.Context, op Operation) error {
+			start := time.Now()
+			err := next(ctx, op)
+			entry := logger.WithFields(logrus.Fields{"operation": op.Name, "repo": op.RepoPath, "duration": time.Since(start), MetadataCorrelationID: CorrelationID(ctx)})
+			if err != nil {
+				entry.Warnf("Operation failed: %v", err)
+			} else {
+				entry.Info("Operation completed")
+			}
+			return err
+		}
+	}
+}
+
+// OperationStats summarizes the calls of one operation.
+type OperationStats struct {
+	Calls    int
+	Errors   int
+	Duration time.Duration // total time spent in the operation
+}
+
+// OperationMetrics collects OperationStats per operation name. Its zero value is ready to use.
+type OperationMetrics struct {
+	mu    sync.Mutex
+	stats map[string]OperationStats
+}
+
+// Stats returns a copy of the statistics collected so far.
+func (m *OperationMetrics) Stats() map[string]OperationStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make(map[string]OperationStats, len(m.stats))
+	for name, s := range m.stats {
+		stats[name] = s
+	}
+	return stats
+}
+
+// MetricsMiddleware records every operation in metrics.
+func MetricsMiddleware(metrics *OperationMetrics) OperationMiddleware {
+	return func(next OpFunc) OpFunc {
+		return func(ctx context.Context, op Operation) error {
+			start := time.Now()
+			err := next(ctx, op)
+
+			metrics.mu.Lock()
+			defer metrics.mu.Unlock()
+			if metrics.stats == nil {
+				metrics.stats = make(map[string]OperationStats)
+			}
+			s := metrics.stats[op.Name]
+			s.Calls++
+			if err != nil {
+				s.Errors++
+			}
+			s.Duration += time.Since(start)
+			metrics.stats[op.Name] = s
+			return err
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// pkg/nodeprop/middleware_test.go
+package nodeprop
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationMiddlewareObservesEveryOperation(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	var observed []string
+	metrics := &OperationMetrics{}
+	npManager := (&NodePropManager{Logger: logrus.New()}).WithOperationMiddleware(
+		func(next OpFunc) OpFunc {
+			return func(ctx context.Context, op Operation) error {
+				observed = append(observed, op.Name+" "+filepath.Base(op.RepoPath))
+				return next(ctx, op)
+			}
+		},
+		MetricsMiddleware(metrics),
+	)
+
+	_, err := npManager.AddWorkflowWithResult(NodePropArguments{RepoPath: repoPath, Workflow: "ci", RequireFile: "go.mod"})
+	assert.NoError(t, err, "A skipped workflow should not fail")
+	assert.Error(t, npManager.DeprecateWorkflow(context.Background(), NodePropArguments{RepoPath: repoPath, Workflow: "ci"}, time.Now()))
+	assert.ErrorIs(t, npManager.DeleteNodeProp(context.Background(), repoPath), ErrNodePropNotFound)
+	assert.Error(t, npManager.ReloadConfig(NodePropArguments{Config: filepath.Join(repoPath, "missing.yml")}))
+
+	base := filepath.Base(repoPath)
+	assert.Equal(t, []string{
+		OperationAddWorkflow + " " + base,
+		OperationDeprecateWorkflow + " " + base,
+		OperationDeleteNodeProp + " " + base,
+		OperationReloadConfig + " .",
+	}, observed, "Every operation should pass through the middleware")
+
+	stats := metrics.Stats()
+	assert.Equal(t, 1, stats[OperationAddWorkflow].Calls)
+	assert.Equal(t, 0, stats[OperationAddWorkflow].Errors)
+	assert.Equal(t, 1, stats[OperationDeleteNodeProp].Errors)
+}
+
+func TestOperationMiddlewareOrder(t *testing.T) {
+	var order []string
+	record := func(name string) OperationMiddleware {
+		return func(next OpFunc) OpFunc {
+			return func(ctx context.Context, op Operation) error {
+				order = append(order, name)
+				return next(ctx, op)
+			}
+		}
+	}
+	refused := errors.New("refused")
+	refuse := func(next OpFunc) OpFunc {
+		return func(context.Context, Operation) error { return refused }
+	}
+
+	npManager := (&NodePropManager{Logger: logrus.New()}).WithOperationMiddleware(record("outer"), record("inner"), refuse)
+	err := npManager.DeleteNodeProp(context.Background(), "/src/api")
+	assert.ErrorIs(t, err, refused, "Middleware should be able to refuse an operation")
+	assert.Equal(t, []string{"outer", "inner"}, order, "The first middleware should run outermost")
+}
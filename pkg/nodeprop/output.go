@@ -0,0 +1,48 @@
+// pkg/nodeprop/output.go
+package nodeprop
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// outputTemplatePrefix introduces a Go template in the --format flag.
+const outputTemplatePrefix = "tmpl="
+
+// outputTemplateFuncs are available to output templates in addition to the text/template builtins.
+var outputTemplateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// ParseOutputTemplate parses a --format value of the form `tmpl=<Go template>`, such as
+// `tmpl={{.Name}} {{join .Triggers ","}}`. Besides the builtins, templates can call join and json.
+func ParseOutputTemplate(format string) (*template.Template, error) {
+	if !strings.HasPrefix(format, outputTemplatePrefix) {
+		return nil, fmt.Errorf("unsupported output format '%s' (expected %s<Go template>)", format, outputTemplatePrefix)
+	}
+	tmpl, err := template.New("format").Funcs(outputTemplateFuncs).Option("missingkey=error").Parse(strings.TrimPrefix(format, outputTemplatePrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// RenderEach executes the template once per item, each followed by a newline.
+func RenderEach[T any](w io.Writer, tmpl *template.Template, items []T) error {
+	for _, item := range items {
+		if err := tmpl.Execute(w, item); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// pkg/nodeprop/output_test.go
+package nodeprop
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderEachWorkflows(t *testing.T) {
+	workflows := []Workflow{
+		{Name: "CI", File: ".github/workflows/ci.yml", Triggers: []string{"push", "pull_request"}, Managed: true},
+		{Name: "Release", File: ".github/workflows/release.yml", Triggers: []string{"push"}},
+	}
+
+	tmpl, err := ParseOutputTemplate(`tmpl={{.Name}}	{{join .Triggers ","}}{{if .Managed}}	managed{{end}}`)
+	assert.NoError(t, err, "ParseOutputTemplate failed")
+
+	var out bytes.Buffer
+	assert.NoError(t, RenderEach(&out, tmpl, workflows), "RenderEach failed")
+	assert.Equal(t, "CI\tpush,pull_request\tmanaged\nRelease\tpush\n", out.String(), "Rendered output mismatch")
+
+	tmpl, err = ParseOutputTemplate(`tmpl={{json .Triggers}}`)
+	assert.NoError(t, err, "ParseOutputTemplate failed")
+	out.Reset()
+	assert.NoError(t, RenderEach(&out, tmpl, workflows[1:]), "RenderEach failed")
+	assert.Equal(t, "[\"push\"]\n", out.String(), "json should encode the value")
+}
+
+func TestParseOutputTemplateErrors(t *testing.T) {
+	_, err := ParseOutputTemplate("yaml")
+	assert.Error(t, err, "Formats other than tmpl= should be rejected")
+
+	_, err = ParseOutputTemplate("tmpl={{.Name")
+	assert.Error(t, err, "Malformed templates should be rejected")
+
+	tmpl, err := ParseOutputTemplate("tmpl={{.Colour}}")
+	assert.NoError(t, err, "ParseOutputTemplate failed")
+	assert.Error(t, RenderEach(&bytes.Buffer{}, tmpl, []Workflow{{Name: "CI"}}), "Unknown fields should fail to render")
+}
@@ -0,0 +1,139 @@
+// pkg/nodeprop/owners.go
+package nodeprop
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// OwnerProfile holds the defaults applied to repositories of one GitHub owner. It mirrors an
+// entry of the `owners` section of the config file.
+type OwnerProfile struct {
+	Domain           string `mapstructure:"domain"`            // domain pattern, e.g. "{repo}.cdaprod.dev"; {repo} is the service name
+	WorkflowTemplate string `mapstructure:"workflow_template"` // named workflow template, e.g. "go-ci"
+}
+
+// DomainFor expands the profile's domain pattern for the repository and validates the result,
+// so patterns that produce invalid hostnames for a repository name are caught before being
+// written. It returns an empty domain when the profile has no pattern.
+func (p OwnerProfile) DomainFor(repo string) (string, error) {
+	if p.Domain == "" {
+		return "", nil
+	}
+	domain := strings.ReplaceAll(p.Domain, "{repo}", strings.ToLower(repo))
+	if err := ValidateDomain(domain); err != nil {
+		return "", fmt.Errorf("domain pattern '%s' does not give a valid hostname for %s: %w", p.Domain, repo, err)
+	}
+	return domain, nil
+}
+
+// defaultOwner is the GitHub owner assumed for repositories without a GitHub remote.
+const defaultOwner = "Cdaprod"
+
+// RepoOwner returns the GitHub owner of the repository: the owner its GitHub remote points to,
+// or defaultOwner when it has none.
+func RepoOwner(repoPath string) string {
+	if owner, _, ok := githubRemote(repoPath); ok {
+		return owner
+	}
+	return defaultOwner
+}
+
+// githubRemote returns the owner and name of the GitHub repository that the `origin` remote of
+// the git repository at repoPath, or its first remote, points to.
+func githubRemote(repoPath string) (owner, repo string, ok bool) {
+	content, err := ioutil.ReadFile(filepath.Join(repoPath, ".git", "config"))
+	if err != nil {
+		return "", "", false
+	}
+
+	var remote string
+	urls := map[string]string{}
+	var order []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			remote = ""
+			if name := strings.TrimPrefix(strings.TrimSuffix(line, "]"), "[remote "); name != line {
+				remote = strings.Trim(name, `"`)
+			}
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if remote == "" || !found || strings.TrimSpace(key) != "url" {
+			continue
+		}
+		if _, seen := urls[remote]; !seen {
+			order = append(order, remote)
+		}
+		urls[remote] = strings.TrimSpace(value)
+	}
+	if len(order) == 0 {
+		return "", "", false
+	}
+	remoteURL, found := urls["origin"]
+	if !found {
+		remoteURL = urls[order[0]]
+	}
+	return parseGitHubURL(remoteURL)
+}
+
+// parseGitHubURL returns the owner and repository of a GitHub remote URL in any of the forms
+// git accepts, e.g. https://github.com/owner/repo.git or git@github.com:owner/repo.git.
+func parseGitHubURL(remoteURL string) (owner, repo string, ok bool) {
+	var host, path string
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return "", "", false
+		}
+		host, path = u.Hostname(), u.Path
+	} else {
+		var found bool
+		if host, path, found = strings.Cut(remoteURL, ":"); !found {
+			return "", "", false
+		}
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	}
+	if !strings.EqualFold(host, "github.com") {
+		return "", "", false
+	}
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(path, "/"), ".git"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// applyOwnerProfile fills the arguments left empty with the defaults of the repository owner's
+// profile. Owner names match case-insensitively; explicit arguments always win.
+func (npm *NodePropManager) applyOwnerProfile(args NodePropArguments) (NodePropArguments, error) {
+	owner := RepoOwner(args.RepoPath)
+	for name, profile := range npm.Owners {
+		if !strings.EqualFold(name, owner) {
+			continue
+		}
+		if args.Domain == "" {
+			service, _ := serviceIdentity(args)
+			domain, err := profile.DomainFor(service)
+			if err != nil {
+				return args, err
+			}
+			if domain != "" {
+				npm.Logger.Debugf("Using the %s owner default domain %s", owner, domain)
+			}
+			args.Domain = domain
+		}
+		if args.Template == "" && profile.WorkflowTemplate != "" {
+			npm.Logger.Debugf("Using the %s owner default workflow template %s", owner, profile.WorkflowTemplate)
+			args.Template = profile.WorkflowTemplate
+		}
+		break
+	}
+	return args, nil
+}
@@ -0,0 +1,101 @@
+// pkg/nodeprop/owners_test.go
+package nodeprop
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnerProfileDomainFor(t *testing.T) {
+	profile := OwnerProfile{Domain: "{repo}.cdaprod.dev"}
+
+	domain, err := profile.DomainFor("Node-Prop")
+	assert.NoError(t, err)
+	assert.Equal(t, "node-prop.cdaprod.dev", domain)
+
+	// Repository names that are not valid DNS labels are rejected
+	for _, repo := range []string{"my_repo", "-edge", strings.Repeat("a", 64)} {
+		_, err := profile.DomainFor(repo)
+		assert.Error(t, err, repo)
+	}
+
+	domain, err = OwnerProfile{}.DomainFor("node-prop")
+	assert.NoError(t, err)
+	assert.Empty(t, domain, "A profile without a pattern should give no domain")
+}
+
+func TestApplyOwnerProfile(t *testing.T) {
+	npManager := &NodePropManager{
+		Logger: logrus.New(),
+		Owners: map[string]OwnerProfile{
+			"cdaprod": {Domain: "{repo}.cdaprod.dev", WorkflowTemplate: "go-ci"},
+			"other":   {Domain: "{repo}.other.dev", WorkflowTemplate: "node-ci"},
+		},
+	}
+
+	args, err := npManager.applyOwnerProfile(NodePropArguments{RepoPath: "/src/api"})
+	assert.NoError(t, err)
+	assert.Equal(t, "api.cdaprod.dev", args.Domain, "Owner names should match case-insensitively")
+	assert.Equal(t, "go-ci", args.Template)
+
+	args, err = npManager.applyOwnerProfile(NodePropArguments{RepoPath: "/src/api", Domain: "api.example.com", Template: "docker"})
+	assert.NoError(t, err)
+	assert.Equal(t, "api.example.com", args.Domain, "Explicit arguments should override owner defaults")
+	assert.Equal(t, "docker", args.Template)
+
+	_, err = npManager.applyOwnerProfile(NodePropArguments{RepoPath: "/src/my_api"})
+	assert.Error(t, err, "A pattern giving an invalid hostname should fail")
+}
+
+func TestRepoOwnerFromGitRemote(t *testing.T) {
+	tempDir := setupTempRepo(t)
+	defer teardownTempRepo(t, tempDir)
+	repoPath := filepath.Join(tempDir, "widgets")
+
+	// Without a GitHub remote the default owner is assumed
+	assert.Equal(t, "Cdaprod", RepoOwner(repoPath))
+
+	gitConfig := "[core]\n\tbare = false\n[remote \"upstream\"]\n\turl = https://github.com/Cdaprod/widgets.git\n[remote \"origin\"]\n\turl = git@github.com:acme/widgets.git\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n"
+	assert.NoError(t, os.MkdirAll(filepath.Join(repoPath, ".git"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(repoPath, ".git", "config"), []byte(gitConfig), 0644))
+
+	assert.Equal(t, "acme", RepoOwner(repoPath), "The origin remote should give the owner")
+	assert.Equal(t, "https://github.com/acme/widgets", RepoAddress(repoPath))
+
+	npManager := &NodePropManager{
+		Logger: logrus.New(),
+		Owners: map[string]OwnerProfile{
+			"cdaprod": {Domain: "{repo}.cdaprod.dev"},
+			"Acme":    {Domain: "{repo}.acme.dev", WorkflowTemplate: "node-ci"},
+		},
+	}
+	args, err := npManager.applyOwnerProfile(NodePropArguments{RepoPath: repoPath})
+	assert.NoError(t, err)
+	assert.Equal(t, "widgets.acme.dev", args.Domain, "The remote owner's profile should apply")
+	assert.Equal(t, "node-ci", args.Template)
+}
+
+func TestParseGitHubURL(t *testing.T) {
+	for _, remoteURL := range []string{
+		"https://github.com/acme/widgets.git",
+		"https://github.com/acme/widgets",
+		"ssh://git@github.com/acme/widgets.git",
+		"git@github.com:acme/widgets.git",
+	} {
+		owner, repo, ok := parseGitHubURL(remoteURL)
+		assert.True(t, ok, remoteURL)
+		assert.Equal(t, "acme", owner, remoteURL)
+		assert.Equal(t, "widgets", repo, remoteURL)
+	}
+
+	for _, remoteURL := range []string{"https://gitlab.com/acme/widgets.git", "/srv/git/widgets.git", "git@github.com:acme"} {
+		_, _, ok := parseGitHubURL(remoteURL)
+		assert.False(t, ok, remoteURL)
+	}
+}
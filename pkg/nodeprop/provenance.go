@@ -0,0 +1,37 @@
+// pkg/nodeprop/provenance.go
+package nodeprop
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Version is the nodeprop version recorded in generated files, set at build time with
+// -ldflags "-X github.com/Cdaprod/nodeprop/pkg/nodeprop.Version=v1.2.3".
+var Version = "dev"
+
+// Invocation sources recorded in Provenance.Source.
+const (
+	SourceCLI = "cli"
+	SourceAPI = "api" // the package used as a library; the default
+)
+
+// provenance describes the run generating a nodeprop file from the workflow template.
+func (npm *NodePropManager) provenance(runID, template string, templateContent []byte) Provenance {
+	source := npm.Source
+	if source == "" {
+		source = SourceAPI
+	}
+	hash := sha256.Sum256(templateContent)
+	return Provenance{
+		Tool:         "nodeprop",
+		Version:      Version,
+		Template:     template,
+		TemplateHash: "sha256:" + hex.EncodeToString(hash[:]),
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		Source:       source,
+		Profile:      npm.Profile,
+		RunID:        runID,
+	}
+}
@@ -0,0 +1,492 @@
+// pkg/nodeprop/runtime.go
+package nodeprop
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// goFrameworks maps Go module path prefixes to the framework they provide.
+var goFrameworks = map[string]string{
+	"github.com/gin-gonic/gin":           "gin",
+	"github.com/labstack/echo":           "echo",
+	"github.com/gofiber/fiber":           "fiber",
+	"github.com/go-chi/chi":              "chi",
+	"github.com/gorilla/mux":             "gorilla/mux",
+	"github.com/spf13/cobra":             "cobra",
+	"google.golang.org/grpc":             "grpc",
+	"github.com/urfave/cli":              "urfave/cli",
+	"github.com/charmbracelet/bubbletea": "bubbletea",
+}
+
+// nodeFrameworks maps npm package names to the framework they provide.
+var nodeFrameworks = map[string]string{
+	"express":       "express",
+	"koa":           "koa",
+	"fastify":       "fastify",
+	"@nestjs/core":  "nestjs",
+	"next":          "next",
+	"nuxt":          "nuxt",
+	"react":         "react",
+	"vue":           "vue",
+	"@angular/core": "angular",
+	"svelte":        "svelte",
+}
+
+// pythonFrameworks maps normalized PyPI package names to the framework they provide.
+var pythonFrameworks = map[string]string{
+	"django":    "django",
+	"flask":     "flask",
+	"fastapi":   "fastapi",
+	"starlette": "starlette",
+	"tornado":   "tornado",
+	"aiohttp":   "aiohttp",
+	"pyramid":   "pyramid",
+}
+
+// rustFrameworks maps crate names to the framework they provide.
+var rustFrameworks = map[string]string{
+	"actix-web": "actix-web",
+	"axum":      "axum",
+	"rocket":    "rocket",
+	"warp":      "warp",
+	"tide":      "tide",
+	"tokio":     "tokio",
+}
+
+var (
+	// tomlSection matches a TOML table header such as [package] or [[bin]].
+	tomlSection = regexp.MustCompile(`^\[\[?\s*([^\]]+?)\s*\]\]?$`)
+	// tomlQuoted matches a quoted TOML string.
+	tomlQuoted = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+	// pythonRequirementName matches the distribution name at the start of a requirement specifier.
+	pythonRequirementName = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)`)
+)
+
+// DetectRuntimes statically inspects the repository for go.mod, package.json,
+// pyproject.toml/requirements.txt and Cargo.toml and returns one Runtime per language found.
+// Nothing in the repository is executed.
+func DetectRuntimes(repoPath string) ([]Runtime, error) {
+	detectors := []func(string) (*Runtime, error){
+		detectGoRuntime,
+		detectNodeRuntime,
+		detectPythonRuntime,
+		detectRustRuntime,
+	}
+
+	var runtimes []Runtime
+	for _, detect := range detectors {
+		runtime, err := detect(repoPath)
+		if err != nil {
+			return runtimes, err
+		}
+		if runtime != nil {
+			sort.Strings(runtime.Frameworks)
+			sort.Strings(runtime.Entrypoints)
+			runtimes = append(runtimes, *runtime)
+		}
+	}
+	return runtimes, nil
+}
+
+// readRepoFile reads a file from the repository, returning nil content when it does not exist.
+func readRepoFile(repoPath, name string) ([]byte, error) {
+	content, err := ioutil.ReadFile(filepath.Join(repoPath, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return content, err
+}
+
+// addFramework appends framework to the runtime unless it is already recorded.
+func (r *Runtime) addFramework(framework string) {
+	for _, existing := range r.Frameworks {
+		if existing == framework {
+			return
+		}
+	}
+	r.Frameworks = append(r.Frameworks, framework)
+}
+
+// detectGoRuntime parses go.mod for the module path, Go version and framework dependencies.
+func detectGoRuntime(repoPath string) (*Runtime, error) {
+	content, err := readRepoFile(repoPath, "go.mod")
+	if err != nil || content == nil {
+		return nil, err
+	}
+
+	runtime := &Runtime{Language: "go"}
+	inRequire := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(line, "// indirect") {
+			continue
+		}
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case inRequire && fields[0] == ")":
+			inRequire = false
+		case inRequire:
+			runtime.addGoDependency(fields[0])
+		case fields[0] == "module" && len(fields) > 1:
+			runtime.Module = strings.Trim(fields[1], `"`)
+		case fields[0] == "go" && len(fields) > 1:
+			runtime.Version = fields[1]
+		case fields[0] == "require" && len(fields) > 1 && fields[1] == "(":
+			inRequire = true
+		case fields[0] == "require" && len(fields) > 1:
+			runtime.addGoDependency(fields[1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read go.mod: %w", err)
+	}
+
+	// Services without a web framework dependency usually serve through the standard library.
+	if usesNetHTTP, err := goImportsNetHTTP(repoPath); err != nil {
+		return nil, err
+	} else if usesNetHTTP && !runtime.hasAnyFramework("gin", "echo", "fiber", "chi", "gorilla/mux") {
+		runtime.addFramework("net/http")
+	}
+
+	if fileExists(filepath.Join(repoPath, "main.go")) {
+		runtime.Entrypoints = append(runtime.Entrypoints, "main.go")
+	}
+	mains, _ := filepath.Glob(filepath.Join(repoPath, "cmd", "*", "main.go"))
+	if cmdMain := filepath.Join(repoPath, "cmd", "main.go"); fileExists(cmdMain) {
+		mains = append(mains, cmdMain)
+	}
+	for _, mainFile := range mains {
+		rel, err := filepath.Rel(repoPath, mainFile)
+		if err == nil {
+			runtime.Entrypoints = append(runtime.Entrypoints, filepath.ToSlash(rel))
+		}
+	}
+	return runtime, nil
+}
+
+// addGoDependency records the framework provided by a required module, if any.
+func (r *Runtime) addGoDependency(module string) {
+	for prefix, framework := range goFrameworks {
+		if module == prefix || strings.HasPrefix(module, prefix+"/") {
+			r.addFramework(framework)
+		}
+	}
+}
+
+// hasAnyFramework reports whether any of the frameworks has been recorded.
+func (r *Runtime) hasAnyFramework(frameworks ...string) bool {
+	for _, framework := range frameworks {
+		for _, existing := range r.Frameworks {
+			if existing == framework {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// goImportsNetHTTP reports whether any non-vendored Go source file imports net/http.
+func goImportsNetHTTP(repoPath string) (bool, error) {
+	found := false
+	err := filepath.WalkDir(repoPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if found {
+			return fs.SkipAll
+		}
+		if d.IsDir() {
+			if p != repoPath && (discoveryExcludedDirs[d.Name()] || d.Name() == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ".go") || strings.HasSuffix(p, "_test.go") {
+			return nil
+		}
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		found = bytes.Contains(content, []byte(`"net/http"`))
+		return nil
+	})
+	return found, err
+}
+
+// fileExists reports whether path exists.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// detectNodeRuntime parses package.json for the engine constraint, scripts, entrypoints and
+// framework dependencies.
+func detectNodeRuntime(repoPath string) (*Runtime, error) {
+	content, err := readRepoFile(repoPath, "package.json")
+	if err != nil || content == nil {
+		return nil, err
+	}
+
+	var pkg struct {
+		Name            string            `json:"name"`
+		Main            string            `json:"main"`
+		Bin             json.RawMessage   `json:"bin"`
+		Engines         map[string]string `json:"engines"`
+		Scripts         map[string]string `json:"scripts"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	runtime := &Runtime{
+		Language: "node",
+		Version:  pkg.Engines["node"],
+		Module:   pkg.Name,
+	}
+	for name := range pkg.Scripts {
+		runtime.Scripts = append(runtime.Scripts, name)
+	}
+	sort.Strings(runtime.Scripts)
+
+	for _, deps := range []map[string]string{pkg.Dependencies, pkg.DevDependencies} {
+		for name := range deps {
+			if framework, ok := nodeFrameworks[name]; ok {
+				runtime.addFramework(framework)
+			}
+		}
+	}
+
+	if pkg.Main != "" {
+		runtime.Entrypoints = append(runtime.Entrypoints, pkg.Main)
+	}
+	// bin is either a single path or a map of command name to path.
+	var bin string
+	var bins map[string]string
+	if json.Unmarshal(pkg.Bin, &bin) == nil && bin != "" {
+		runtime.Entrypoints = append(runtime.Entrypoints, bin)
+	} else if json.Unmarshal(pkg.Bin, &bins) == nil {
+		for _, path := range bins {
+			runtime.Entrypoints = append(runtime.Entrypoints, path)
+		}
+	}
+	return runtime, nil
+}
+
+// parseTOMLSections is a minimal, static TOML reader sufficient for pyproject.toml and
+// Cargo.toml. It returns each table's keys mapped to their raw values, with multi-line
+// arrays joined onto one line. Repeated tables such as [[bin]] share one entry.
+func parseTOMLSections(content []byte) map[string]map[string]string {
+	sections := map[string]map[string]string{"": {}}
+	section := ""
+	pendingKey, depth := "", 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if pendingKey != "" {
+			sections[section][pendingKey] += " " + line
+			if depth += tomlBracketDepth(line); depth <= 0 {
+				pendingKey = ""
+			}
+			continue
+		}
+
+		if match := tomlSection.FindStringSubmatch(line); match != nil {
+			section = match[1]
+			if sections[section] == nil {
+				sections[section] = map[string]string{}
+			}
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.Trim(strings.TrimSpace(parts[0]), `"'`)
+		value := strings.TrimSpace(parts[1])
+		if existing, ok := sections[section][key]; ok {
+			value = existing + " " + value
+		}
+		sections[section][key] = value
+		if depth = tomlBracketDepth(value); depth > 0 {
+			pendingKey = key
+		}
+	}
+	return sections
+}
+
+// tomlBracketDepth returns how many more array brackets a line of TOML opens than it closes,
+// ignoring brackets inside quoted strings, such as extras in "uvicorn[standard]", and comments.
+func tomlBracketDepth(line string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return depth
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth
+}
+
+// tomlStrings extracts every quoted string from a raw TOML value.
+func tomlStrings(value string) []string {
+	var values []string
+	for _, match := range tomlQuoted.FindAllStringSubmatch(value, -1) {
+		values = append(values, match[1]+match[2])
+	}
+	return values
+}
+
+// normalizePythonName normalizes a distribution name per PEP 503.
+func normalizePythonName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "-", ".", "-").Replace(name))
+}
+
+// detectPythonRuntime parses pyproject.toml (PEP 621 or Poetry) and requirements.txt for the
+// Python constraint, framework dependencies and console scripts.
+func detectPythonRuntime(repoPath string) (*Runtime, error) {
+	pyproject, err := readRepoFile(repoPath, "pyproject.toml")
+	if err != nil {
+		return nil, err
+	}
+	requirements, err := readRepoFile(repoPath, "requirements.txt")
+	if err != nil {
+		return nil, err
+	}
+	if pyproject == nil && requirements == nil {
+		return nil, nil
+	}
+
+	runtime := &Runtime{Language: "python"}
+	addRequirement := func(requirement string) {
+		if match := pythonRequirementName.FindStringSubmatch(requirement); match != nil {
+			if framework, ok := pythonFrameworks[normalizePythonName(match[1])]; ok {
+				runtime.addFramework(framework)
+			}
+		}
+	}
+
+	if pyproject != nil {
+		sections := parseTOMLSections(pyproject)
+
+		project := sections["project"]
+		runtime.Module = firstString(project["name"])
+		runtime.Version = firstString(project["requires-python"])
+		for _, requirement := range tomlStrings(project["dependencies"]) {
+			addRequirement(requirement)
+		}
+		for name := range sections["project.scripts"] {
+			runtime.Entrypoints = append(runtime.Entrypoints, name)
+		}
+
+		poetry := sections["tool.poetry"]
+		if runtime.Module == "" {
+			runtime.Module = firstString(poetry["name"])
+		}
+		for name, value := range sections["tool.poetry.dependencies"] {
+			if name == "python" {
+				if runtime.Version == "" {
+					runtime.Version = firstString(value)
+				}
+				continue
+			}
+			addRequirement(name)
+		}
+		for name := range sections["tool.poetry.scripts"] {
+			runtime.Entrypoints = append(runtime.Entrypoints, name)
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(requirements))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
+			continue
+		}
+		addRequirement(line)
+	}
+
+	for _, script := range []string{"manage.py", "main.py", "app.py"} {
+		if fileExists(filepath.Join(repoPath, script)) {
+			runtime.Entrypoints = append(runtime.Entrypoints, script)
+		}
+	}
+	return runtime, nil
+}
+
+// firstString returns the first quoted string in a raw TOML value.
+func firstString(value string) string {
+	if values := tomlStrings(value); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// detectRustRuntime parses Cargo.toml for the package name, rust-version, framework crates
+// and binary targets.
+func detectRustRuntime(repoPath string) (*Runtime, error) {
+	content, err := readRepoFile(repoPath, "Cargo.toml")
+	if err != nil || content == nil {
+		return nil, err
+	}
+
+	sections := parseTOMLSections(content)
+	runtime := &Runtime{
+		Language: "rust",
+		Module:   firstString(sections["package"]["name"]),
+		Version:  firstString(sections["package"]["rust-version"]),
+	}
+	for name := range sections["dependencies"] {
+		if framework, ok := rustFrameworks[name]; ok {
+			runtime.addFramework(framework)
+		}
+	}
+
+	for _, path := range tomlStrings(sections["bin"]["path"]) {
+		runtime.Entrypoints = append(runtime.Entrypoints, path)
+	}
+	if len(runtime.Entrypoints) == 0 && fileExists(filepath.Join(repoPath, "src", "main.rs")) {
+		runtime.Entrypoints = append(runtime.Entrypoints, "src/main.rs")
+	}
+	return runtime, nil
+}
@@ -0,0 +1,114 @@
+// pkg/nodeprop/runtime_test.go
+package nodeprop
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectRuntimes(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		expected []Runtime
+	}{
+		{
+			fixture: "go",
+			expected: []Runtime{{
+				Language:    "go",
+				Version:     "1.21",
+				Module:      "github.com/Cdaprod/example-api",
+				Frameworks:  []string{"gin", "grpc"},
+				Entrypoints: []string{"cmd/api/main.go"},
+			}},
+		},
+		{
+			fixture: "node",
+			expected: []Runtime{{
+				Language:    "node",
+				Version:     ">=18",
+				Module:      "example-web",
+				Frameworks:  []string{"express", "react"},
+				Entrypoints: []string{"bin/cli.js", "server.js"},
+				Scripts:     []string{"start", "test"},
+			}},
+		},
+		{
+			fixture: "python",
+			expected: []Runtime{{
+				Language:    "python",
+				Version:     ">=3.10",
+				Module:      "example-service",
+				Frameworks:  []string{"fastapi", "flask"},
+				Entrypoints: []string{"example-service", "manage.py"},
+			}},
+		},
+		{
+			// Extras on the first line of a multi-line array
+			fixture: "extras",
+			expected: []Runtime{{
+				Language:    "python",
+				Version:     ">=3.11",
+				Module:      "example-extras",
+				Frameworks:  []string{"fastapi", "starlette"},
+				Entrypoints: []string{"example-extras"},
+			}},
+		},
+		{
+			fixture: "poetry",
+			expected: []Runtime{{
+				Language:    "python",
+				Version:     "^3.11",
+				Module:      "example-poetry",
+				Frameworks:  []string{"django"},
+				Entrypoints: []string{"serve"},
+			}},
+		},
+		{
+			fixture: "rust",
+			expected: []Runtime{{
+				Language:    "rust",
+				Version:     "1.70",
+				Module:      "example-rs",
+				Frameworks:  []string{"axum", "tokio"},
+				Entrypoints: []string{"src/main.rs"},
+			}},
+		},
+		{
+			// A Go service using the standard library alongside a Vue frontend
+			fixture: "multi",
+			expected: []Runtime{
+				{
+					Language:    "go",
+					Version:     "1.20",
+					Module:      "example.com/multi",
+					Frameworks:  []string{"net/http"},
+					Entrypoints: []string{"main.go"},
+				},
+				{
+					Language:   "node",
+					Module:     "multi-frontend",
+					Frameworks: []string{"vue"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			runtimes, err := DetectRuntimes(filepath.Join("testdata", "runtime", tt.fixture))
+			assert.NoError(t, err, "DetectRuntimes failed")
+			assert.Equal(t, tt.expected, runtimes, "Detected runtimes mismatch")
+		})
+	}
+}
+
+func TestDetectRuntimesEmptyRepository(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	runtimes, err := DetectRuntimes(repoPath)
+	assert.NoError(t, err, "DetectRuntimes failed")
+	assert.Empty(t, runtimes, "No runtimes should be detected in an empty repository")
+}
@@ -0,0 +1,304 @@
+// pkg/nodeprop/signature.go
+package nodeprop
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// signatureFileSuffix is appended to a nodeprop file's path for its detached signature.
+const signatureFileSuffix = ".sig"
+
+var (
+	// ErrUnsigned is returned when a nodeprop file carries no signature.
+	ErrUnsigned = errors.New("nodeprop file is not signed")
+	// ErrInvalidSignature is returned when no trusted key verifies a nodeprop file's signature.
+	ErrInvalidSignature = errors.New("nodeprop file signature is not valid for any trusted key")
+)
+
+// canonicalNodeProp returns the canonical bytes that are signed for a nodeprop document: the
+// document as parsed, with metadata.signature removed, re-encoded with its mapping keys
+// sorted. Every key is signed, including ones this version of nodeprop does not know, and the
+// result does not depend on the NodePropFile struct or on the document's formatting.
+func canonicalNodeProp(document []byte) ([]byte, error) {
+	var parsed map[interface{}]interface{}
+	if err := yaml.Unmarshal(document, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid nodeprop document: %w", err)
+	}
+	if metadata, ok := parsed["metadata"].(map[interface{}]interface{}); ok {
+		delete(metadata, "signature")
+		if len(metadata) == 0 {
+			// A metadata section holding only the signature was added by signing.
+			delete(parsed, "metadata")
+		}
+	}
+	return yaml.Marshal(parsed)
+}
+
+// SignNodeProp signs the canonicalized nodeprop document with key and returns the
+// base64-encoded signature. It does not modify nodeProp.
+func SignNodeProp(nodeProp NodePropFile, key ed25519.PrivateKey) (string, error) {
+	document, err := yaml.Marshal(&nodeProp)
+	if err != nil {
+		return "", err
+	}
+	return SignNodePropDocument(document, key)
+}
+
+// SignNodePropDocument signs the canonical form of a raw nodeprop document with key and
+// returns the base64-encoded signature.
+func SignNodePropDocument(document []byte, key ed25519.PrivateKey) (string, error) {
+	canonical, err := canonicalNodeProp(document)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, canonical)), nil
+}
+
+// VerifyNodeProp checks a base64-encoded signature over the canonicalized nodeprop document
+// against the trusted public keys.
+func VerifyNodeProp(nodeProp NodePropFile, signature string, trusted []ed25519.PublicKey) error {
+	document, err := yaml.Marshal(&nodeProp)
+	if err != nil {
+		return err
+	}
+	return VerifyNodePropDocument(document, signature, trusted)
+}
+
+// VerifyNodePropDocument checks a base64-encoded signature over the canonical form of a raw
+// nodeprop document against the trusted public keys.
+func VerifyNodePropDocument(document []byte, signature string, trusted []ed25519.PublicKey) error {
+	signature = strings.TrimSpace(signature)
+	if signature == "" {
+		return ErrUnsigned
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	canonical, err := canonicalNodeProp(document)
+	if err != nil {
+		return err
+	}
+	for _, key := range trusted {
+		if ed25519.Verify(key, canonical, sig) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// setDocumentSignature stores signature under metadata.signature of a raw nodeprop document,
+// keeping its other keys, known or not, in their order.
+func setDocumentSignature(document []byte, signature string) ([]byte, error) {
+	var parsed yaml.MapSlice
+	if err := yaml.Unmarshal(document, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid nodeprop document: %w", err)
+	}
+
+	metadataIndex := -1
+	for i, item := range parsed {
+		if item.Key == "metadata" {
+			metadataIndex = i
+		}
+	}
+	if metadataIndex < 0 {
+		parsed = append(parsed, yaml.MapItem{Key: "metadata"})
+		metadataIndex = len(parsed) - 1
+	}
+	metadata, _ := parsed[metadataIndex].Value.(yaml.MapSlice)
+
+	replaced := false
+	for i := range metadata {
+		if metadata[i].Key == "signature" {
+			metadata[i].Value, replaced = signature, true
+		}
+	}
+	if !replaced {
+		metadata = append(metadata, yaml.MapItem{Key: "signature", Value: signature})
+	}
+	parsed[metadataIndex].Value = metadata
+	return yaml.Marshal(parsed)
+}
+
+// SignNodePropFile signs every document of the nodeprop file at path, storing each signature
+// inline under metadata.signature or, when detached is set, in path + ".sig". A detached
+// signature covers a single document, so multi-document files can only be signed inline, and
+// signing inline removes a detached signature left from before.
+func SignNodePropFile(path string, key ed25519.PrivateKey, detached bool) error {
+	return signNodePropFile(OSFS{}, ioutil.ReadFile, path, key, detached)
+}
+
+// SignFile signs the nodeprop file at path like SignNodePropFile, with the configured key and
+// signing mode, writing through Files.
+func (npm *NodePropManager) SignFile(ctx context.Context, path string) error {
+	return npm.runOperation(ctx, Operation{Name: OperationSignNodeProp, RepoPath: filepath.Dir(path)}, func(ctx context.Context, op Operation) error {
+		ctx, cancel := npm.operationContext(ctx, OperationSignNodeProp)
+		defer cancel()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if npm.SigningKey == nil {
+			return fmt.Errorf("no signing key is configured to sign %s", path)
+		}
+		unlock, err := npm.LockRepo(ctx, op.RepoPath)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		return signNodePropFile(npm.files(), npm.readFile, path, npm.SigningKey, npm.SignDetached)
+	})
+}
+
+// signNodePropFile implements SignNodePropFile, reading with readFile and writing through files.
+func signNodePropFile(files WriteFS, readFile func(string) ([]byte, error), path string, key ed25519.PrivateKey, detached bool) error {
+	content, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	nodeProps, err := ParseNodePropDocuments(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse '%s': %w", path, err)
+	}
+	if len(nodeProps) == 0 {
+		return fmt.Errorf("'%s' has no nodeprop documents to sign", path)
+	}
+
+	if detached {
+		if len(nodeProps) > 1 {
+			return fmt.Errorf("'%s' has %d nodeprop documents; sign it inline instead of with a detached signature", path, len(nodeProps))
+		}
+		signature, err := SignNodePropDocument(splitYAMLDocuments(content)[0], key)
+		if err != nil {
+			return err
+		}
+		return files.WriteFile(path+signatureFileSuffix, []byte(signature+"\n"), 0644)
+	}
+
+	for i, document := range splitYAMLDocuments(content) {
+		signature, err := SignNodePropDocument(document, key)
+		if err != nil {
+			return err
+		}
+		signed, err := setDocumentSignature(document, signature)
+		if err != nil {
+			return err
+		}
+		if content, err = replaceYAMLDocument(content, i, signed); err != nil {
+			return err
+		}
+	}
+	if err := files.WriteFile(path, content, 0644); err != nil {
+		return err
+	}
+	if _, err := readFile(path + signatureFileSuffix); err == nil {
+		return files.Remove(path + signatureFileSuffix)
+	}
+	return nil
+}
+
+// VerifyNodePropFile verifies every document of the nodeprop file at path against the trusted
+// public keys, using its detached signature when path + ".sig" exists and the inline ones
+// otherwise.
+func VerifyNodePropFile(path string, trusted []ed25519.PublicKey) error {
+	return verifyNodePropFile(ioutil.ReadFile, path, trusted)
+}
+
+// verifyNodePropFile implements VerifyNodePropFile, reading with readFile.
+func verifyNodePropFile(readFile func(string) ([]byte, error), path string, trusted []ed25519.PublicKey) error {
+	content, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	nodeProps, err := ParseNodePropDocuments(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse '%s': %w", path, err)
+	}
+	if len(nodeProps) == 0 {
+		return ErrUnsigned
+	}
+	documents := splitYAMLDocuments(content)
+
+	detached, err := readFile(path + signatureFileSuffix)
+	if err == nil {
+		if len(nodeProps) > 1 {
+			return fmt.Errorf("%w: a detached signature cannot cover the %d documents of '%s'", ErrInvalidSignature, len(nodeProps), path)
+		}
+		return VerifyNodePropDocument(documents[0], string(detached), trusted)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	for i, nodeProp := range nodeProps {
+		if err := VerifyNodePropDocument(documents[i], nodeProp.Metadata.Signature, trusted); err != nil {
+			if len(nodeProps) == 1 {
+				return err
+			}
+			return fmt.Errorf("nodeprop document %d (%s): %w", i+1, nodeProp.Name, err)
+		}
+	}
+	return nil
+}
+
+// LoadSigningKey reads a PEM-encoded PKCS#8 ed25519 private key, as produced by
+// `openssl genpkey -algorithm ed25519`.
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEMFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key '%s': %w", path, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key '%s' is not an ed25519 key", path)
+	}
+	return privateKey, nil
+}
+
+// LoadTrustedKeys reads PEM-encoded PKIX ed25519 public keys from each of paths.
+func LoadTrustedKeys(paths []string) ([]ed25519.PublicKey, error) {
+	keys := make([]ed25519.PublicKey, 0, len(paths))
+	for _, path := range paths {
+		block, err := readPEMFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trusted key '%s': %w", path, err)
+		}
+		publicKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("trusted key '%s' is not an ed25519 key", path)
+		}
+		keys = append(keys, publicKey)
+	}
+	return keys, nil
+}
+
+// readPEMFile reads the first PEM block from the file at path.
+func readPEMFile(path string) (*pem.Block, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in '%s'", path)
+	}
+	return block, nil
+}
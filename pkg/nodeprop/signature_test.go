@@ -0,0 +1,236 @@
+// pkg/nodeprop/signature_test.go
+package nodeprop
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+// writeTestNodeProp writes a nodeprop file into dir and returns its path
+func writeTestNodeProp(t *testing.T, dir string) string {
+	content, err := yaml.Marshal(&NodePropFile{
+		ID:     "test-id",
+		Name:   "test-service",
+		Status: "active",
+		CustomProperties: CustomProperties{
+			Domain: "test.domain",
+		},
+	})
+	assert.NoError(t, err, "Failed to marshal nodeprop file")
+
+	path := filepath.Join(dir, ".nodeprop.yml")
+	err = ioutil.WriteFile(path, content, 0644)
+	assert.NoError(t, err, "Failed to write nodeprop file")
+	return path
+}
+
+func TestSignAndVerifyNodePropFile(t *testing.T) {
+	dir := setupTempRepo(t)
+	defer teardownTempRepo(t, dir)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err, "Failed to generate key")
+	otherPublicKey, _, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err, "Failed to generate key")
+
+	path := writeTestNodeProp(t, dir)
+
+	// Unsigned files are reported as such
+	err = VerifyNodePropFile(path, []ed25519.PublicKey{publicKey})
+	assert.ErrorIs(t, err, ErrUnsigned, "Unsigned file should fail verification")
+
+	// Inline signature verifies only against the matching key
+	err = SignNodePropFile(path, privateKey, false)
+	assert.NoError(t, err, "SignNodePropFile failed")
+
+	nodeProps, err := LoadNodePropFiles(path)
+	assert.NoError(t, err, "Failed to read signed file")
+	nodeProp := nodeProps[0]
+	assert.NotEmpty(t, nodeProp.Metadata.Signature, "Signature should be stored inline")
+
+	err = VerifyNodePropFile(path, []ed25519.PublicKey{otherPublicKey, publicKey})
+	assert.NoError(t, err, "Signed file should verify against a trusted key")
+
+	err = VerifyNodePropFile(path, []ed25519.PublicKey{otherPublicKey})
+	assert.ErrorIs(t, err, ErrInvalidSignature, "Signed file should not verify against an untrusted key")
+
+	// Tampering with the document invalidates the signature
+	nodeProp.CustomProperties.Domain = "forged.domain"
+	content, err := yaml.Marshal(&nodeProp)
+	assert.NoError(t, err, "Failed to marshal tampered file")
+	err = ioutil.WriteFile(path, content, 0644)
+	assert.NoError(t, err, "Failed to write tampered file")
+
+	err = VerifyNodePropFile(path, []ed25519.PublicKey{publicKey})
+	assert.ErrorIs(t, err, ErrInvalidSignature, "Tampered file should fail verification")
+}
+
+func TestSignNodePropFileDetached(t *testing.T) {
+	dir := setupTempRepo(t)
+	defer teardownTempRepo(t, dir)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err, "Failed to generate key")
+
+	path := writeTestNodeProp(t, dir)
+	original, err := ioutil.ReadFile(path)
+	assert.NoError(t, err, "Failed to read nodeprop file")
+
+	err = SignNodePropFile(path, privateKey, true)
+	assert.NoError(t, err, "SignNodePropFile failed")
+
+	signed, err := ioutil.ReadFile(path)
+	assert.NoError(t, err, "Failed to read nodeprop file")
+	assert.Equal(t, original, signed, "Detached signing should not modify the nodeprop file")
+
+	_, err = os.Stat(path + ".sig")
+	assert.NoError(t, err, "Detached signature file not created")
+
+	err = VerifyNodePropFile(path, []ed25519.PublicKey{publicKey})
+	assert.NoError(t, err, "Detached signature should verify")
+}
+
+func TestSignNodePropFileMultiDocument(t *testing.T) {
+	dir := setupTempRepo(t)
+	defer teardownTempRepo(t, dir)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err, "Failed to generate key")
+
+	path := filepath.Join(dir, ".nodeprop.yml")
+	content := "# services of the monorepo\nid: api\nname: api\nstatus: active\n---\nid: worker\nname: worker\nstatus: active\n"
+	err = ioutil.WriteFile(path, []byte(content), 0644)
+	assert.NoError(t, err, "Failed to write nodeprop file")
+
+	// Detached signatures cover a single document only
+	err = SignNodePropFile(path, privateKey, true)
+	assert.Error(t, err, "Detached signing of a multi-document file should fail")
+	_, err = os.Stat(path + ".sig")
+	assert.True(t, os.IsNotExist(err), "No detached signature should be written")
+
+	// Every document is signed inline and kept
+	err = SignNodePropFile(path, privateKey, false)
+	assert.NoError(t, err, "SignNodePropFile failed")
+
+	signed, err := ioutil.ReadFile(path)
+	assert.NoError(t, err, "Failed to read signed file")
+	assert.Contains(t, string(signed), "# services of the monorepo", "Header comment should be kept")
+
+	nodeProps, err := LoadNodePropFiles(path)
+	assert.NoError(t, err, "Failed to parse signed file")
+	assert.Equal(t, 2, len(nodeProps), "Both documents should be kept")
+	for _, nodeProp := range nodeProps {
+		assert.NotEmpty(t, nodeProp.Metadata.Signature, "Document %s should be signed", nodeProp.Name)
+	}
+
+	err = VerifyNodePropFile(path, []ed25519.PublicKey{publicKey})
+	assert.NoError(t, err, "Signed documents should verify")
+
+	// Tampering with the second document is reported against it
+	tampered, err := ReplaceNodePropDocument(signed, 1, NodePropFile{
+		ID:       "worker",
+		Name:     "worker",
+		Status:   "deprecated",
+		Metadata: Metadata{Signature: nodeProps[1].Metadata.Signature},
+	})
+	assert.NoError(t, err, "Failed to tamper with document")
+	err = ioutil.WriteFile(path, tampered, 0644)
+	assert.NoError(t, err, "Failed to write tampered file")
+
+	err = VerifyNodePropFile(path, []ed25519.PublicKey{publicKey})
+	assert.ErrorIs(t, err, ErrInvalidSignature, "Tampered document should fail verification")
+	assert.Contains(t, err.Error(), "document 2", "Error should name the tampered document")
+}
+
+func TestSignNodePropFileUnknownKeys(t *testing.T) {
+	dir := setupTempRepo(t)
+	defer teardownTempRepo(t, dir)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err, "Failed to generate key")
+
+	path := filepath.Join(dir, ".nodeprop.yml")
+	content := "id: api\nname: api\nstatus: active\nsla:\n  tier: gold\nmetadata:\n  owner: platform\n"
+	err = ioutil.WriteFile(path, []byte(content), 0644)
+	assert.NoError(t, err, "Failed to write nodeprop file")
+
+	err = SignNodePropFile(path, privateKey, false)
+	assert.NoError(t, err, "SignNodePropFile failed")
+
+	signed, err := ioutil.ReadFile(path)
+	assert.NoError(t, err, "Failed to read signed file")
+	assert.Contains(t, string(signed), "tier: gold", "Unknown keys should be kept")
+
+	err = VerifyNodePropFile(path, []ed25519.PublicKey{publicKey})
+	assert.NoError(t, err, "Signed file should verify")
+
+	// Unknown keys are covered by the signature
+	tampered := strings.Replace(string(signed), "tier: gold", "tier: platinum", 1)
+	err = ioutil.WriteFile(path, []byte(tampered), 0644)
+	assert.NoError(t, err, "Failed to write tampered file")
+
+	err = VerifyNodePropFile(path, []ed25519.PublicKey{publicKey})
+	assert.ErrorIs(t, err, ErrInvalidSignature, "Changing an unknown key should fail verification")
+}
+
+func TestLoadSigningAndTrustedKeys(t *testing.T) {
+	dir := setupTempRepo(t)
+	defer teardownTempRepo(t, dir)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err, "Failed to generate key")
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	assert.NoError(t, err, "Failed to marshal private key")
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	assert.NoError(t, err, "Failed to marshal public key")
+
+	privatePath := filepath.Join(dir, "nodeprop.key")
+	publicPath := filepath.Join(dir, "nodeprop.pub")
+	err = ioutil.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600)
+	assert.NoError(t, err, "Failed to write private key")
+	err = ioutil.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644)
+	assert.NoError(t, err, "Failed to write public key")
+
+	loadedPrivate, err := LoadSigningKey(privatePath)
+	assert.NoError(t, err, "LoadSigningKey failed")
+	assert.Equal(t, privateKey, loadedPrivate, "Loaded private key mismatch")
+
+	loadedPublic, err := LoadTrustedKeys([]string{publicPath})
+	assert.NoError(t, err, "LoadTrustedKeys failed")
+	assert.Equal(t, []ed25519.PublicKey{publicKey}, loadedPublic, "Loaded public keys mismatch")
+}
+
+func TestSignFileTimeout(t *testing.T) {
+	memFS, repoPath := setupMemRepo(t)
+	nodePropPath := filepath.Join(repoPath, ".nodeprop.yml")
+	content := []byte("id: api\nname: api\n")
+	assert.NoError(t, memFS.WriteFile(nodePropPath, content, 0644))
+
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err, "Failed to generate key")
+	npManager := &NodePropManager{
+		SigningKey: privateKey,
+		Timeouts:   Timeouts{Operations: map[string]time.Duration{OperationSignNodeProp: time.Nanosecond}},
+		Files:      memFS,
+		Logger:     logrus.New(),
+	}
+
+	err = npManager.SignFile(context.Background(), nodePropPath)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "SignFile should be bounded by its timeout")
+	unchanged, err := memFS.ReadFile(fsPath(nodePropPath))
+	assert.NoError(t, err)
+	assert.Equal(t, content, unchanged, "Nothing should be signed after the timeout")
+}
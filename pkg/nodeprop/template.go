@@ -0,0 +1,76 @@
+// pkg/nodeprop/template.go
+package nodeprop
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+
+	"github.com/Cdaprod/nodeprop/assets"
+	"gopkg.in/yaml.v2"
+)
+
+// embeddedNodePropTemplate is the name reported for the embedded .empty.nodeprop.yml.
+const embeddedNodePropTemplate = "embedded:.empty.nodeprop.yml"
+
+// yamlLinePattern extracts the line number from yaml.v2 error messages.
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// TemplateError reports a .nodeprop.yml template that does not parse into a NodePropFile.
+type TemplateError struct {
+	Path string
+	Line int // 0 when the parser did not report a line
+	Err  error
+}
+
+func (e *TemplateError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("malformed nodeprop template '%s' at line %d: %v", e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("malformed nodeprop template '%s': %v", e.Path, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// ParseNodePropTemplate parses template content into a NodePropFile, returning a
+// *TemplateError naming the file and offending line when it is malformed.
+func ParseNodePropTemplate(path string, content []byte) (NodePropFile, error) {
+	var nodeProp NodePropFile
+	if err := yaml.Unmarshal(content, &nodeProp); err != nil {
+		templateErr := &TemplateError{Path: path, Err: err}
+		if match := yamlLinePattern.FindStringSubmatch(err.Error()); match != nil {
+			templateErr.Line, _ = strconv.Atoi(match[1])
+		}
+		return NodePropFile{}, templateErr
+	}
+	return nodeProp, nil
+}
+
+// LoadNodePropTemplate reads the template at path and validates that it parses into a NodePropFile.
+func LoadNodePropTemplate(path string) (NodePropFile, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return NodePropFile{}, err
+	}
+	return ParseNodePropTemplate(path, content)
+}
+
+// loadNodePropTemplate loads the configured template, falling back to the embedded
+// default with a warning when TemplateFallback is enabled.
+func (npm *NodePropManager) loadNodePropTemplate() (NodePropFile, error) {
+	nodeProp, err := LoadNodePropTemplate(npm.GlobalNodePropPath)
+	if err == nil {
+		return nodeProp, nil
+	}
+
+	if !npm.TemplateFallback {
+		npm.Logger.Errorf("Failed to load nodeprop template: %v", err)
+		return NodePropFile{}, err
+	}
+
+	npm.Logger.Warnf("Failed to load nodeprop template, falling back to embedded default: %v", err)
+	return ParseNodePropTemplate(embeddedNodePropTemplate, assets.EmptyNodeProp)
+}
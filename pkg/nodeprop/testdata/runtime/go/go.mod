@@ -0,0 +1,11 @@
+module github.com/Cdaprod/example-api
+
+go 1.21
+
+require (
+	github.com/gin-gonic/gin v1.9.1
+	github.com/spf13/cobra v1.8.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require google.golang.org/grpc v1.60.0
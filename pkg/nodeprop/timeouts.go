@@ -0,0 +1,45 @@
+// pkg/nodeprop/timeouts.go
+package nodeprop
+
+import (
+	"context"
+	"time"
+)
+
+// Names of the manager operations that can be given their own timeout.
+const (
+	OperationAddWorkflow       = "add_workflow"
+	OperationDeleteNodeProp    = "delete_nodeprop"
+	OperationDeprecateWorkflow = "deprecate_workflow"
+	OperationSignNodeProp      = "sign_nodeprop"
+)
+
+// Operations lists every operation name accepted in Timeouts.Operations.
+var Operations = []string{OperationAddWorkflow, OperationDeleteNodeProp, OperationDeprecateWorkflow, OperationSignNodeProp}
+
+// Timeouts bounds how long manager operations may run. It mirrors the `timeouts` section of
+// the config file; zero durations mean no timeout.
+type Timeouts struct {
+	Default    time.Duration            // timeouts.default
+	Operations map[string]time.Duration // timeouts.<operation>, overriding Default
+}
+
+// For returns the effective timeout of the operation.
+func (t Timeouts) For(operation string) time.Duration {
+	if timeout := t.Operations[operation]; timeout > 0 {
+		return timeout
+	}
+	return t.Default
+}
+
+// operationContext derives the context an operation runs under, bounded by its effective
+// timeout when one is configured.
+func (npm *NodePropManager) operationContext(ctx context.Context, operation string) (context.Context, context.CancelFunc) {
+	timeout := npm.Timeouts.For(operation)
+	if timeout <= 0 {
+		npm.logger(ctx).Debugf("Running %s without a timeout", operation)
+		return context.WithCancel(ctx)
+	}
+	npm.logger(ctx).Debugf("Running %s with a %s timeout", operation, timeout)
+	return context.WithTimeout(ctx, timeout)
+}
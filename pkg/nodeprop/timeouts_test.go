@@ -0,0 +1,47 @@
+// pkg/nodeprop/timeouts_test.go
+package nodeprop
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutsFor(t *testing.T) {
+	timeouts := Timeouts{
+		Default:    time.Minute,
+		Operations: map[string]time.Duration{OperationAddWorkflow: 10 * time.Second, OperationDeleteNodeProp: 0},
+	}
+	assert.Equal(t, 10*time.Second, timeouts.For(OperationAddWorkflow), "Operation timeout should override the default")
+	assert.Equal(t, time.Minute, timeouts.For(OperationDeleteNodeProp), "Zero operation timeout should fall back to the default")
+	assert.Equal(t, time.Duration(0), Timeouts{}.For(OperationAddWorkflow), "No timeout should be configured by default")
+}
+
+func TestAddWorkflowTimeout(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	npManager := &NodePropManager{
+		GlobalNodePropPath:   filepath.Join("..", "..", "assets", ".empty.nodeprop.yml"),
+		WorkflowTemplatePath: filepath.Join("..", "..", "assets", "default_workflow", "index-nodeprop-workflow.yml"),
+		Timeouts: Timeouts{
+			Default:    time.Hour,
+			Operations: map[string]time.Duration{OperationAddWorkflow: 50 * time.Millisecond},
+		},
+		Logger: logrus.New(),
+	}
+
+	// The simulated wait for the workflow is cancelled at the add_workflow timeout
+	start := time.Now()
+	err := npManager.AddWorkflow(NodePropArguments{RepoPath: repoPath, Workflow: "slow"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "AddWorkflow should time out")
+	assert.Less(t, time.Since(start), 5*time.Second, "AddWorkflow should be cancelled at its configured timeout")
+
+	_, err = os.Stat(filepath.Join(repoPath, ".nodeprop.yml"))
+	assert.True(t, os.IsNotExist(err), ".nodeprop.yml should not be written after a timeout")
+}
@@ -6,6 +6,7 @@ type NodePropFile struct {
 	ID               string           `yaml:"id"`
 	Name             string           `yaml:"name"`
 	Address          string           `yaml:"address"`
+	Addresses        map[string]string `yaml:"addresses,omitempty"` // extra addresses by kind, e.g. registry, rendered from address_templates
 	Capabilities     []string         `yaml:"capabilities"`
 	Status           string           `yaml:"status"`
 	Metadata         Metadata         `yaml:"metadata"`
@@ -20,6 +21,43 @@ type Metadata struct {
 	Tags        []string `yaml:"tags"`
 	GitHub      GitHub   `yaml:"github"`
 	Docker      Docker   `yaml:"docker"`
+	Runtime     []Runtime `yaml:"runtime,omitempty"`
+	Workflows   []Workflow `yaml:"workflows,omitempty"`
+	GeneratedBy Provenance `yaml:"generated_by,omitempty"`
+	Signature   string   `yaml:"signature,omitempty"` // base64 ed25519 signature over the rest of the document
+}
+
+// Runtime describes a language runtime statically detected in the repository
+type Runtime struct {
+	Language    string   `yaml:"language"`
+	Version     string   `yaml:"version"` // version constraint, e.g. "1.20" or ">=18"
+	Module      string   `yaml:"module"`
+	Frameworks  []string `yaml:"frameworks"`
+	Entrypoints []string `yaml:"entrypoints"`
+	Scripts     []string `yaml:"scripts,omitempty"`
+}
+
+// Workflow describes a GitHub Actions workflow found in the repository
+type Workflow struct {
+	Name     string   `yaml:"name"`
+	File     string   `yaml:"file"` // path relative to the repository root
+	Triggers []string `yaml:"triggers"`
+	Jobs     []string `yaml:"jobs"`
+	Badge    string   `yaml:"badge"`
+	Managed  bool     `yaml:"managed"` // added by nodeprop rather than discovered
+	Sunset   string   `yaml:"sunset,omitempty"` // YYYY-MM-DD removal date of a deprecated workflow
+}
+
+// Provenance records the nodeprop run that generated the file
+type Provenance struct {
+	Tool         string `yaml:"tool"`
+	Version      string `yaml:"version"`
+	Template     string `yaml:"template"`      // workflow template name or path
+	TemplateHash string `yaml:"template_hash"` // sha256 of the workflow template as read
+	Timestamp    string `yaml:"timestamp"`
+	Source       string `yaml:"source"` // cli or api
+	Profile      string `yaml:"profile,omitempty"` // configuration file in use
+	RunID        string `yaml:"run_id"`
 }
 
 // GitHub metadata about the repository.
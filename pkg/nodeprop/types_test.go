@@ -0,0 +1,93 @@
+// pkg/nodeprop/types_test.go
+package nodeprop
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// benchNodePropFile returns a fully populated NodePropFile for benchmarks
+func benchNodePropFile() NodePropFile {
+	return NodePropFile{
+		ID:           "3f1c9a52-7d0e-4b4a-9a43-2d5e8f0b6c11",
+		Name:         "nodeprop",
+		Address:      "https://github.com/Cdaprod/nodeprop",
+		Capabilities: []string{"go", "docker", "workflow"},
+		Status:       "active",
+		Metadata: Metadata{
+			Description: "Dynamic workflow management",
+			Owner:       "Cdaprod",
+			LastUpdated: "2024-01-01T00:00:00Z",
+			Tags:        []string{"automation", "github"},
+			GitHub: GitHub{
+				Stars:        42,
+				Forks:        7,
+				Issues:       3,
+				PullRequests: PRInfo{Open: 2, Closed: 19},
+				LatestCommit: "9fceb02",
+				License:      "MIT",
+				Topics:       []string{"go", "workflows"},
+			},
+			Docker: Docker{
+				Dockerfile: DockerfileInfo{
+					ExposedPorts: []string{"8080"},
+					EnvVars:      []string{"LOG_LEVEL=info"},
+					Cmd:          "nodeprop",
+					Entrypoint:   "/usr/local/bin/nodeprop",
+					Volumes:      []string{"/data"},
+				},
+				DockerCompose: DockerCompose{
+					Services: []Service{
+						{Name: "api", Ports: []string{"8080:8080"}, EnvVars: []string{"ENV=prod"}, Volumes: []string{"data:/data"}},
+					},
+					Ports:   map[string][]int{"api": {8080}},
+					Volumes: map[string][]string{"api": {"data:/data"}},
+					EnvVars: map[string][]string{"api": {"ENV=prod"}},
+					Command: map[string]string{"api": "nodeprop serve"},
+				},
+			},
+		},
+		CustomProperties: CustomProperties{
+			DeployEnvironment: "production",
+			MonitoringEnabled: true,
+			Service:           "api",
+			App:               "nodeprop",
+			Image:             "cdaprod/nodeprop:latest",
+			Ports:             []string{"8080"},
+			Volumes:           []string{"data"},
+			Network:           "backend",
+			Domain:            "nodeprop.cdaprod.dev",
+		},
+	}
+}
+
+func BenchmarkNodePropFileMarshal(b *testing.B) {
+	nodeProp := benchNodePropFile()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := yaml.Marshal(&nodeProp); err != nil {
+			b.Fatalf("Failed to marshal NodePropFile: %v", err)
+		}
+	}
+}
+
+func BenchmarkNodePropFileUnmarshal(b *testing.B) {
+	nodeProp := benchNodePropFile()
+	content, err := yaml.Marshal(&nodeProp)
+	if err != nil {
+		b.Fatalf("Failed to marshal NodePropFile: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(content)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var decoded NodePropFile
+		if err := yaml.Unmarshal(content, &decoded); err != nil {
+			b.Fatalf("Failed to unmarshal NodePropFile: %v", err)
+		}
+	}
+}
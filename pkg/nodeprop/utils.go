@@ -1,5 +1,33 @@
 // pkg/nodeprop/utils.go
 package nodeprop
 
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
 // Utility functions can be added here as needed.
-// For example, functions to validate input, format data, etc.
\ No newline at end of file
+// For example, functions to validate input, format data, etc.
+
+// CheckFile reports whether the file at the relative path exists in the repository. Paths that
+// are absolute or leave the repository, such as "../../etc/passwd", are refused.
+func CheckFile(repoPath, path string) (bool, error) {
+	return checkFile(os.Stat, repoPath, path)
+}
+
+// checkFile implements CheckFile, looking the file up with stat.
+func checkFile(stat func(string) (fs.FileInfo, error), repoPath, path string) (bool, error) {
+	if !filepath.IsLocal(path) {
+		return false, fmt.Errorf("file '%s' must be a relative path inside the repository", path)
+	}
+	_, err := stat(filepath.Join(repoPath, path))
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,507 @@
+// pkg/nodeprop/workflow.go
+package nodeprop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// defaultWorkflowPermissions is the least-privilege permissions block injected when none is configured.
+var defaultWorkflowPermissions = map[string]string{"contents": "read"}
+
+// WorkflowPolicy controls the boilerplate injected into workflows added by the manager.
+// It mirrors the `workflows` section of the config file.
+type WorkflowPolicy struct {
+	EnforcePermissions bool              // workflows.enforce_permissions
+	EnforceConcurrency bool              // workflows.enforce_concurrency
+	DefaultPermissions map[string]string // workflows.default_permissions
+	Normalize          bool              // workflows.normalize
+}
+
+// ApplyWorkflowPolicy injects a top-level `permissions:` and/or `concurrency:` block into the
+// workflow content when the policy requires it and the workflow does not declare one itself.
+// Explicit declarations are never overridden. It returns the updated content and the names of
+// the blocks that were injected.
+func ApplyWorkflowPolicy(content []byte, policy WorkflowPolicy, repo, workflow string) ([]byte, []string, error) {
+	if !policy.EnforcePermissions && !policy.EnforceConcurrency {
+		return content, nil, nil
+	}
+
+	var declared map[string]interface{}
+	if err := yaml.Unmarshal(content, &declared); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+
+	var blocks yaml.MapSlice
+	var injected []string
+
+	if _, ok := declared["permissions"]; policy.EnforcePermissions && !ok {
+		permissions := policy.DefaultPermissions
+		if len(permissions) == 0 {
+			permissions = defaultWorkflowPermissions
+		}
+		scopes := make([]string, 0, len(permissions))
+		for scope := range permissions {
+			scopes = append(scopes, scope)
+		}
+		sort.Strings(scopes)
+
+		var block yaml.MapSlice
+		for _, scope := range scopes {
+			block = append(block, yaml.MapItem{Key: scope, Value: permissions[scope]})
+		}
+		blocks = append(blocks, yaml.MapItem{Key: "permissions", Value: block})
+		injected = append(injected, "permissions")
+	}
+
+	if _, ok := declared["concurrency"]; policy.EnforceConcurrency && !ok {
+		// The group is encoded by yaml.Marshal, which quotes repository and workflow names
+		// holding characters such as "#" or ": ".
+		group := fmt.Sprintf("%s-%s-${{ github.ref }}", repo, workflow)
+		blocks = append(blocks, yaml.MapItem{Key: "concurrency", Value: yaml.MapSlice{{Key: "group", Value: group}}})
+		injected = append(injected, "concurrency")
+	}
+
+	if len(blocks) == 0 {
+		return content, nil, nil
+	}
+
+	var updated []byte
+	var err error
+	if isFlowMapping(content) {
+		updated, err = insertWorkflowBlocksFlow(content, blocks)
+	} else {
+		updated, err = insertWorkflowBlocks(content, blocks)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Make sure the insertion produced a valid workflow declaring every injected block.
+	var result map[string]interface{}
+	if err := yaml.Unmarshal(updated, &result); err != nil {
+		return nil, nil, fmt.Errorf("injecting %s produced an invalid workflow: %w", strings.Join(injected, " and "), err)
+	}
+	for _, name := range injected {
+		if _, ok := result[name]; !ok {
+			return nil, nil, fmt.Errorf("injecting %s into the workflow failed", name)
+		}
+	}
+	return updated, injected, nil
+}
+
+// insertWorkflowBlocks inserts the blocks into a block-style workflow right before its
+// top-level `jobs:` key, or appends them when there is none, leaving the rest of the text and
+// its comments untouched.
+func insertWorkflowBlocks(content []byte, blocks yaml.MapSlice) ([]byte, error) {
+	lines := strings.SplitAfter(string(content), "\n")
+	insertAt := len(lines)
+	for i, line := range lines {
+		if strings.HasPrefix(line, "jobs:") {
+			insertAt = i
+			break
+		}
+	}
+
+	var out strings.Builder
+	for _, line := range lines[:insertAt] {
+		out.WriteString(line)
+	}
+	if insertAt == len(lines) && out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+		out.WriteString("\n")
+	}
+	for _, block := range blocks {
+		encoded, err := yaml.Marshal(yaml.MapSlice{block})
+		if err != nil {
+			return nil, err
+		}
+		out.Write(encoded)
+		out.WriteString("\n")
+	}
+	for _, line := range lines[insertAt:] {
+		out.WriteString(line)
+	}
+	return []byte(out.String()), nil
+}
+
+// insertWorkflowBlocksFlow adds the blocks, in flow style, to the end of a workflow written as
+// a single flow mapping, such as `{on: push, jobs: {...}}`. The workflow is not re-encoded, as
+// yaml.v2 would turn its `on` key into `true`.
+func insertWorkflowBlocksFlow(content []byte, blocks yaml.MapSlice) ([]byte, error) {
+	text := string(content)
+	end := len(text)
+	// Skip trailing blank and comment lines to find the line closing the mapping.
+	for end > 0 {
+		start := strings.LastIndex(text[:end], "\n") + 1
+		line := strings.TrimSpace(text[start:end])
+		if line != "" && !strings.HasPrefix(line, "#") {
+			break
+		}
+		end = start - 1
+		if end < 0 {
+			end = 0
+		}
+	}
+	closing := strings.LastIndex(text[:end], "}")
+	if closing < 0 {
+		return nil, errors.New("failed to find the end of the workflow mapping")
+	}
+
+	var out strings.Builder
+	out.WriteString(strings.TrimRight(text[:closing], " \t\n"))
+	for _, block := range blocks {
+		value, err := flowYAML(block.Value)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&out, ", %s: %s", block.Key, value)
+	}
+	out.WriteString(text[closing:])
+	return []byte(out.String()), nil
+}
+
+// flowYAML encodes a string or a yaml.MapSlice of them in YAML flow style, quoting every
+// string as JSON does, which YAML reads back unchanged.
+func flowYAML(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case string:
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	case yaml.MapSlice:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			key, err := flowYAML(fmt.Sprint(item.Key))
+			if err != nil {
+				return "", err
+			}
+			encoded, err := flowYAML(item.Value)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, key+": "+encoded)
+		}
+		return "{" + strings.Join(items, ", ") + "}", nil
+	}
+	return "", fmt.Errorf("cannot encode %T in flow style", value)
+}
+
+// isFlowMapping reports whether the workflow is written as a single flow mapping.
+func isFlowMapping(content []byte) bool {
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "---" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return strings.HasPrefix(line, "{")
+	}
+	return false
+}
+
+// NormalizeWorkflowContent converts CRLF (and lone CR) line endings to LF, trims trailing
+// spaces and tabs from every line and ends the content with exactly one newline, so templates
+// edited on different platforms produce identical workflow files.
+func NormalizeWorkflowContent(content []byte) []byte {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text = strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return []byte(text + "\n")
+}
+
+// DiscoverWorkflows describes every GitHub Actions workflow in workflowDir, a directory of the
+// repository (`.github/workflows` when empty), with badge URLs under repoURL. Workflows that
+// cannot be parsed are skipped and reported in the returned error alongside the ones that could.
+func DiscoverWorkflows(repoPath, workflowDir, repoURL string) ([]Workflow, error) {
+	return discoverWorkflows(os.DirFS(repoPath), workflowDir, repoURL)
+}
+
+// discoverWorkflows implements DiscoverWorkflows over the repository filesystem repo.
+func discoverWorkflows(repo fs.FS, workflowDir, repoURL string) ([]Workflow, error) {
+	entries, err := readWorkflowDir(repo, workflowDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var workflows []Workflow
+	var errs []error
+	for _, entry := range entries {
+		content, err := fs.ReadFile(repo, entry)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		name := path.Base(entry)
+		workflow, err := parseWorkflow(content)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if workflow.Name == "" {
+			workflow.Name = name
+		}
+		workflow.File = entry
+		workflow.Badge = fmt.Sprintf("%s/actions/workflows/%s/badge.svg", repoURL, name)
+		workflows = append(workflows, workflow)
+	}
+	return workflows, errors.Join(errs...)
+}
+
+// readWorkflowDir returns the slash-separated paths of the workflow files in workflowDir of the
+// repository filesystem repo (`.github/workflows` when empty); none when it does not exist.
+func readWorkflowDir(repo fs.FS, workflowDir string) ([]string, error) {
+	dir := ".github/workflows"
+	if workflowDir != "" {
+		dir = path.Clean(filepath.ToSlash(workflowDir))
+	}
+	entries, err := fs.ReadDir(repo, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+		files = append(files, path.Join(dir, entry.Name()))
+	}
+	return files, nil
+}
+
+// parseWorkflow extracts the name, triggers, job IDs and sunset date of a workflow. Anchors
+// and aliases are resolved by the YAML decoder, and jobs calling reusable workflows through
+// `uses:` are listed like any other job.
+func parseWorkflow(content []byte) (Workflow, error) {
+	var doc map[interface{}]interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return Workflow{}, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+
+	var workflow Workflow
+	if name, ok := doc["name"].(string); ok {
+		workflow.Name = name
+	}
+
+	// YAML 1.1 decodes an unquoted `on` key as the boolean true.
+	on, ok := doc["on"]
+	if !ok {
+		on = doc[true]
+	}
+	switch triggers := on.(type) {
+	case string:
+		workflow.Triggers = []string{triggers}
+	case []interface{}:
+		for _, trigger := range triggers {
+			workflow.Triggers = append(workflow.Triggers, fmt.Sprint(trigger))
+		}
+	case map[interface{}]interface{}:
+		workflow.Triggers = sortedKeys(triggers)
+	}
+
+	if jobs, ok := doc["jobs"].(map[interface{}]interface{}); ok {
+		workflow.Jobs = sortedKeys(jobs)
+	}
+	workflow.Sunset = workflowSunset(content)
+	return workflow, nil
+}
+
+// sortedKeys returns the keys of a decoded YAML mapping as sorted strings.
+func sortedKeys(m map[interface{}]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, fmt.Sprint(key))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// BadgeMarkdown renders a shields.io status badge for the workflow, linked to its runs on
+// GitHub, as markdown for pasting into a README. repoURL is the repository's GitHub URL.
+func (w Workflow) BadgeMarkdown(repoURL string) string {
+	file := path.Base(w.File)
+	slug := strings.TrimPrefix(repoURL, "https://github.com/")
+	return fmt.Sprintf("[![%s](https://img.shields.io/github/actions/workflow/status/%s/%s?label=%s)](%s/actions/workflows/%s)",
+		w.Name, slug, file, url.PathEscape(w.Name), repoURL, file)
+}
+
+// Kinds of WorkflowChange.
+const (
+	WorkflowChangeAdded   = "added"
+	WorkflowChangeRemoved = "removed"
+	WorkflowChangeChanged = "changed"
+)
+
+// WorkflowChange is a semantic difference between two workflows, described as what applying
+// the local workflow over the remote one would change.
+type WorkflowChange struct {
+	Path string // e.g. "jobs.build.steps[1].run"
+	Kind string // added, removed or changed
+	Old  string // remote value, empty when added
+	New  string // local value, empty when removed
+}
+
+// DiffWorkflow compares two workflows semantically: formatting, comments, key order, anchors
+// and the equivalent `on: push`, `on: [push]` and `on: {push: }` trigger forms do not count as
+// changes. Mapping keys are visited in sorted order and list items by index, so the changes
+// come out in a stable order.
+func (npm *NodePropManager) DiffWorkflow(local, remote string) ([]WorkflowChange, error) {
+	localTree, err := normalizeWorkflow(local)
+	if err != nil {
+		return nil, fmt.Errorf("local workflow: %w", err)
+	}
+	remoteTree, err := normalizeWorkflow(remote)
+	if err != nil {
+		return nil, fmt.Errorf("remote workflow: %w", err)
+	}
+
+	var changes []WorkflowChange
+	diffWorkflowValues("", remoteTree, localTree, &changes)
+	return changes, nil
+}
+
+// normalizeWorkflow decodes a workflow into string-keyed maps and lists, with the triggers in
+// their mapping form.
+func normalizeWorkflow(content string) (map[string]interface{}, error) {
+	var doc map[interface{}]interface{}
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+
+	normalized := map[string]interface{}{}
+	for key, value := range doc {
+		name := fmt.Sprint(key)
+		// YAML 1.1 decodes an unquoted `on` key as the boolean true.
+		if key == true {
+			name = "on"
+		}
+		normalized[name] = normalizeYAMLValue(value)
+	}
+
+	switch triggers := normalized["on"].(type) {
+	case string:
+		normalized["on"] = map[string]interface{}{triggers: nil}
+	case []interface{}:
+		events := map[string]interface{}{}
+		for _, trigger := range triggers {
+			events[fmt.Sprint(trigger)] = nil
+		}
+		normalized["on"] = events
+	}
+	return normalized, nil
+}
+
+// normalizeYAMLValue converts decoded YAML mappings to string-keyed maps, recursively.
+func normalizeYAMLValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalizeYAMLValue(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = normalizeYAMLValue(item)
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+// diffWorkflowValues appends the changes turning old into new at path.
+func diffWorkflowValues(path string, old, new interface{}, changes *[]WorkflowChange) {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		keys := make([]string, 0, len(oldMap)+len(newMap))
+		for key := range oldMap {
+			keys = append(keys, key)
+		}
+		for key := range newMap {
+			if _, ok := oldMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			oldValue, inOld := oldMap[key]
+			newValue, inNew := newMap[key]
+			switch {
+			case !inOld:
+				*changes = append(*changes, WorkflowChange{Path: child, Kind: WorkflowChangeAdded, New: renderWorkflowValue(newValue)})
+			case !inNew:
+				*changes = append(*changes, WorkflowChange{Path: child, Kind: WorkflowChangeRemoved, Old: renderWorkflowValue(oldValue)})
+			default:
+				diffWorkflowValues(child, oldValue, newValue, changes)
+			}
+		}
+		return
+	}
+
+	oldList, oldIsList := old.([]interface{})
+	newList, newIsList := new.([]interface{})
+	if oldIsList && newIsList {
+		for i := 0; i < len(oldList) || i < len(newList); i++ {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(oldList):
+				*changes = append(*changes, WorkflowChange{Path: child, Kind: WorkflowChangeAdded, New: renderWorkflowValue(newList[i])})
+			case i >= len(newList):
+				*changes = append(*changes, WorkflowChange{Path: child, Kind: WorkflowChangeRemoved, Old: renderWorkflowValue(oldList[i])})
+			default:
+				diffWorkflowValues(child, oldList[i], newList[i], changes)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, WorkflowChange{Path: path, Kind: WorkflowChangeChanged, Old: renderWorkflowValue(old), New: renderWorkflowValue(new)})
+	}
+}
+
+// renderWorkflowValue renders a value for a WorkflowChange: scalars as-is, mappings and lists
+// as YAML.
+func renderWorkflowValue(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		out, err := yaml.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return strings.TrimSpace(string(out))
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,173 @@
+// pkg/nodeprop/workflow_deprecation.go
+package nodeprop
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// sunsetLayout is the date format of workflow sunset dates.
+const sunsetLayout = "2006-01-02"
+
+// Lifecycle states of a workflow, see Workflow.State.
+const (
+	WorkflowStateActive     = "active"
+	WorkflowStateDeprecated = "deprecated" // has a sunset date that has not been reached
+	WorkflowStateExpired    = "expired"    // its sunset date has been reached
+)
+
+// deprecationComment is the first line of a deprecated workflow, recording its sunset date.
+var deprecationComment = regexp.MustCompile(`^# nodeprop: deprecated, sunset (\d{4}-\d{2}-\d{2})`)
+
+// workflowSunset returns the sunset date recorded in the workflow content, if any.
+func workflowSunset(content []byte) string {
+	firstLine := strings.SplitN(string(content), "\n", 2)[0]
+	if match := deprecationComment.FindStringSubmatch(firstLine); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// DeprecateWorkflowContent marks the workflow content as deprecated by prepending a comment
+// with the sunset date, replacing the comment of an earlier deprecation.
+func DeprecateWorkflowContent(content []byte, sunset time.Time) []byte {
+	text := string(content)
+	if workflowSunset(content) != "" {
+		text = ""
+		if i := strings.IndexByte(string(content), '\n'); i >= 0 {
+			text = string(content[i+1:])
+		}
+	}
+	comment := fmt.Sprintf("# nodeprop: deprecated, sunset %s. This workflow will be removed on that date.\n", sunset.Format(sunsetLayout))
+	return []byte(comment + text)
+}
+
+// State reports whether the workflow is active, deprecated or past its sunset date at now.
+func (w Workflow) State(now time.Time) string {
+	if w.Sunset == "" {
+		return WorkflowStateActive
+	}
+	sunset, err := time.Parse(sunsetLayout, w.Sunset)
+	if err != nil || now.Before(sunset) {
+		return WorkflowStateDeprecated
+	}
+	return WorkflowStateExpired
+}
+
+// DeprecateWorkflow marks args.Workflow, in args.Directory of the repository (default
+// `.github/workflows`), as deprecated until sunset, and records the sunset date on its entry in
+// the .nodeprop.yml of args.RepoPath or, with args.Path, of the monorepo service, re-signing a
+// signed file (inline or detached) with the configured key.
+func (npm *NodePropManager) DeprecateWorkflow(ctx context.Context, args NodePropArguments, sunset time.Time) error {
+	return npm.runOperation(ctx, Operation{Name: OperationDeprecateWorkflow, RepoPath: args.RepoPath}, func(ctx context.Context, op Operation) error {
+		return npm.deprecateWorkflow(ctx, args, sunset)
+	})
+}
+
+// deprecateWorkflow implements DeprecateWorkflow.
+func (npm *NodePropManager) deprecateWorkflow(ctx context.Context, args NodePropArguments, sunset time.Time) error {
+	ctx, cancel := npm.operationContext(ctx, OperationDeprecateWorkflow)
+	defer cancel()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if args.Path != "" && !filepath.IsLocal(args.Path) {
+		return fmt.Errorf("nodeprop path '%s' must be a relative path inside the repository", args.Path)
+	}
+	repoPath := args.RepoPath
+	unlock, err := npm.LockRepo(ctx, repoPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	workflowPath, err := workflowFilePath(args)
+	if err != nil {
+		return err
+	}
+	content, err := npm.readFile(workflowPath)
+	if err != nil {
+		return fmt.Errorf("failed to read workflow: %w", err)
+	}
+
+	// Prepare the .nodeprop.yml update before touching the workflow, so a file that cannot be
+	// re-signed leaves both untouched.
+	nodePropPath := filepath.Join(repoPath, args.Path, ".nodeprop.yml")
+	var nodePropYAML []byte
+	var detachedSignature string
+	existing, readErr := npm.readFile(nodePropPath)
+	if readErr == nil {
+		documents, err := ParseNodePropDocuments(existing)
+		if err != nil {
+			return err
+		}
+		if len(documents) == 0 {
+			return fmt.Errorf("%s contains no nodeprop document", nodePropPath)
+		}
+		nodeProp := documents[0]
+		rel, _ := filepath.Rel(repoPath, workflowPath)
+		for i := range nodeProp.Metadata.Workflows {
+			if nodeProp.Metadata.Workflows[i].File == filepath.ToSlash(rel) {
+				nodeProp.Metadata.Workflows[i].Sunset = sunset.Format(sunsetLayout)
+			}
+		}
+		_, statErr := npm.statFile(nodePropPath + signatureFileSuffix)
+		detached := statErr == nil
+		if nodeProp.Metadata.Signature != "" || detached {
+			if npm.SigningKey == nil {
+				return fmt.Errorf("%s is signed and no signing key is configured to re-sign it", nodePropPath)
+			}
+			signature, err := SignNodeProp(nodeProp, npm.SigningKey)
+			if err != nil {
+				return err
+			}
+			if detached {
+				detachedSignature = signature
+			} else {
+				nodeProp.Metadata.Signature = signature
+			}
+		}
+		if nodePropYAML, err = ReplaceNodePropDocument(existing, 0, nodeProp); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(readErr) {
+		return readErr
+	}
+
+	// Show both changes before writing either, so declining one leaves both files untouched.
+	deprecated := DeprecateWorkflowContent(content, sunset)
+	if err := npm.reviewChange(repoPath, workflowPath, content, deprecated); err != nil {
+		return err
+	}
+	if nodePropYAML != nil {
+		if err := npm.reviewChange(repoPath, nodePropPath, existing, nodePropYAML); err != nil {
+			return err
+		}
+	}
+
+	if err := npm.files().WriteFile(workflowPath, deprecated, 0644); err != nil {
+		npm.logger(ctx).Errorf("Failed to write workflow file: %v", err)
+		return err
+	}
+	if nodePropYAML != nil {
+		if err := npm.files().WriteFile(nodePropPath, nodePropYAML, 0644); err != nil {
+			npm.logger(ctx).Errorf("Failed to write .nodeprop.yml: %v", err)
+			return err
+		}
+	}
+	if detachedSignature != "" {
+		if err := npm.files().WriteFile(nodePropPath+signatureFileSuffix, []byte(detachedSignature+"\n"), 0644); err != nil {
+			npm.logger(ctx).Errorf("Failed to write detached signature: %v", err)
+			return err
+		}
+	}
+
+	npm.logger(ctx).Infof("Workflow '%s' in repository '%s' deprecated until %s", args.Workflow, repoPath, sunset.Format(sunsetLayout))
+	npm.emit(ctx, Event{Type: EventTypeSuccess, Message: fmt.Sprintf("deprecated workflow '%s' until %s", workflowPath, sunset.Format(sunsetLayout))})
+	return nil
+}
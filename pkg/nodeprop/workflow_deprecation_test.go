@@ -0,0 +1,172 @@
+// pkg/nodeprop/workflow_deprecation_test.go
+package nodeprop
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestDeprecateWorkflowContent(t *testing.T) {
+	workflow := "name: CI\non: push\n"
+
+	content := DeprecateWorkflowContent([]byte(workflow), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, "2025-01-01", workflowSunset(content), "Sunset date should be recorded")
+	assert.Equal(t, "# nodeprop: deprecated, sunset 2025-01-01. This workflow will be removed on that date.\n"+workflow, string(content))
+
+	// Deprecating again replaces the earlier comment
+	content = DeprecateWorkflowContent(content, time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, "2025-06-30", workflowSunset(content), "Sunset date should be replaced")
+	assert.Equal(t, "# nodeprop: deprecated, sunset 2025-06-30. This workflow will be removed on that date.\n"+workflow, string(content))
+
+	assert.Empty(t, workflowSunset([]byte(workflow)), "Undeprecated workflows have no sunset date")
+}
+
+func TestWorkflowState(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, WorkflowStateActive, Workflow{}.State(now))
+	assert.Equal(t, WorkflowStateDeprecated, Workflow{Sunset: "2025-01-02"}.State(now))
+	assert.Equal(t, WorkflowStateExpired, Workflow{Sunset: "2025-01-01"}.State(now), "A workflow expires on its sunset date")
+	assert.Equal(t, WorkflowStateExpired, Workflow{Sunset: "2024-12-31"}.State(now))
+}
+
+func TestDeprecateWorkflow(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	workflowPath := filepath.Join(repoPath, ".github", "workflows", "ci.yml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(workflowPath), 0755))
+	assert.NoError(t, ioutil.WriteFile(workflowPath, []byte("name: CI\non: push\njobs:\n  build: {}\n"), 0644))
+
+	nodeProp := NodePropFile{Name: "api", Metadata: Metadata{Workflows: []Workflow{{Name: "CI", File: ".github/workflows/ci.yml"}}}}
+	content, err := yaml.Marshal(&nodeProp)
+	assert.NoError(t, err)
+	nodePropPath := filepath.Join(repoPath, ".nodeprop.yml")
+	assert.NoError(t, ioutil.WriteFile(nodePropPath, content, 0644))
+
+	npManager := &NodePropManager{Logger: logrus.New()}
+	sunset := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, npManager.DeprecateWorkflow(context.Background(), NodePropArguments{RepoPath: repoPath, Workflow: "ci"}, sunset), "DeprecateWorkflow failed")
+
+	documents, err := LoadNodePropFiles(nodePropPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-01-01", documents[0].Metadata.Workflows[0].Sunset, "Sunset should be recorded in .nodeprop.yml")
+
+	workflows, err := DiscoverWorkflows(repoPath, "", "https://github.com/Cdaprod/api")
+	assert.NoError(t, err)
+	if assert.Len(t, workflows, 1) {
+		assert.Equal(t, WorkflowStateDeprecated, workflows[0].State(sunset.Add(-time.Hour)))
+		assert.Equal(t, WorkflowStateExpired, workflows[0].State(sunset.Add(time.Hour)))
+	}
+}
+
+func TestDeprecateWorkflowSignedWithoutKey(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	workflow := []byte("name: CI\non: push\n")
+	workflowPath := filepath.Join(repoPath, ".github", "workflows", "ci.yml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(workflowPath), 0755))
+	assert.NoError(t, ioutil.WriteFile(workflowPath, workflow, 0644))
+	content, err := yaml.Marshal(&NodePropFile{Name: "api", Metadata: Metadata{Signature: "c2lnbmF0dXJl"}})
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(repoPath, ".nodeprop.yml"), content, 0644))
+
+	npManager := &NodePropManager{Logger: logrus.New()}
+	err = npManager.DeprecateWorkflow(context.Background(), NodePropArguments{RepoPath: repoPath, Workflow: "ci"}, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.ErrorContains(t, err, "no signing key", "A signed file should not be modified without a key")
+
+	unchanged, err := ioutil.ReadFile(workflowPath)
+	assert.NoError(t, err)
+	assert.Equal(t, workflow, unchanged, "The workflow should be left untouched")
+}
+
+func TestDeprecateWorkflowServiceDirectory(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	workflowPath := filepath.Join(repoPath, "ci", "ci.yml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(workflowPath), 0755))
+	assert.NoError(t, ioutil.WriteFile(workflowPath, []byte("name: CI\non: push\n"), 0644))
+	content, err := yaml.Marshal(&NodePropFile{Name: "api", Metadata: Metadata{Workflows: []Workflow{{Name: "CI", File: "ci/ci.yml"}}}})
+	assert.NoError(t, err)
+	nodePropPath := filepath.Join(repoPath, "services", "api", ".nodeprop.yml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(nodePropPath), 0755))
+	assert.NoError(t, ioutil.WriteFile(nodePropPath, content, 0644))
+
+	npManager := &NodePropManager{Logger: logrus.New()}
+	args := NodePropArguments{RepoPath: repoPath, Workflow: "ci", Path: filepath.Join("services", "api"), Directory: "ci"}
+	assert.NoError(t, npManager.DeprecateWorkflow(context.Background(), args, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	workflow, err := ioutil.ReadFile(workflowPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-01-01", workflowSunset(workflow), "The workflow in the configured directory should be deprecated")
+	documents, err := LoadNodePropFiles(nodePropPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-01-01", documents[0].Metadata.Workflows[0].Sunset, "Sunset should be recorded in the service's .nodeprop.yml")
+
+	args.Path = ".."
+	assert.Error(t, npManager.DeprecateWorkflow(context.Background(), args, time.Now()), "Paths outside the repository should be refused")
+}
+
+func TestDeprecateWorkflowCancelled(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	workflow := []byte("name: CI\non: push\n")
+	workflowPath := filepath.Join(repoPath, ".github", "workflows", "ci.yml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(workflowPath), 0755))
+	assert.NoError(t, ioutil.WriteFile(workflowPath, workflow, 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	npManager := &NodePropManager{Timeouts: Timeouts{Default: time.Minute}, Logger: logrus.New()}
+	err := npManager.DeprecateWorkflow(ctx, NodePropArguments{RepoPath: repoPath, Workflow: "ci"}, time.Now())
+	assert.ErrorIs(t, err, context.Canceled, "A cancelled deprecation should not run")
+
+	unchanged, err := ioutil.ReadFile(workflowPath)
+	assert.NoError(t, err)
+	assert.Equal(t, workflow, unchanged, "The workflow should be left untouched")
+}
+
+func TestDeprecateWorkflowReviewChange(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	workflow := []byte("name: CI\non: push\n")
+	workflowPath := filepath.Join(repoPath, ".github", "workflows", "ci.yml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(workflowPath), 0755))
+	assert.NoError(t, ioutil.WriteFile(workflowPath, workflow, 0644))
+	nodeProp, err := yaml.Marshal(&NodePropFile{Name: "api", Metadata: Metadata{Workflows: []Workflow{{Name: "CI", File: ".github/workflows/ci.yml"}}}})
+	assert.NoError(t, err)
+	nodePropPath := filepath.Join(repoPath, ".nodeprop.yml")
+	assert.NoError(t, ioutil.WriteFile(nodePropPath, nodeProp, 0644))
+
+	var reviewed []string
+	npManager := &NodePropManager{
+		Logger: logrus.New(),
+		ReviewChange: func(path, diff string) bool {
+			reviewed = append(reviewed, diff)
+			return len(reviewed) < 2
+		},
+	}
+	err = npManager.DeprecateWorkflow(context.Background(), NodePropArguments{RepoPath: repoPath, Workflow: "ci"}, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.ErrorIs(t, err, ErrChangeDeclined, "A declined change should abort the deprecation")
+
+	if assert.Len(t, reviewed, 2, "The workflow and .nodeprop.yml should both be reviewed") {
+		assert.Contains(t, reviewed[0], "+# nodeprop: deprecated, sunset 2025-01-01.")
+		assert.Contains(t, reviewed[1], "+++ b/.nodeprop.yml\n")
+		assert.Contains(t, reviewed[1], "sunset: \"2025-01-01\"")
+	}
+	unchanged, err := ioutil.ReadFile(workflowPath)
+	assert.NoError(t, err)
+	assert.Equal(t, workflow, unchanged, "Declining the .nodeprop.yml change should leave the workflow untouched")
+}
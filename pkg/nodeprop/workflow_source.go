@@ -0,0 +1,119 @@
+// pkg/nodeprop/workflow_source.go
+package nodeprop
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxWorkflowSourceSize bounds the workflow content fetched from a URL.
+const maxWorkflowSourceSize = 1 << 20
+
+// workflowSourceClient fetches workflow content from URLs.
+var workflowSourceClient = http.DefaultClient
+
+// workflowContentTypes are the media types accepted for workflow content fetched from a URL.
+// Raw file hosts such as raw.githubusercontent.com and gist raw URLs serve text/plain.
+var workflowContentTypes = map[string]bool{
+	"text/plain":               true,
+	"text/yaml":                true,
+	"text/x-yaml":              true,
+	"application/yaml":         true,
+	"application/x-yaml":       true,
+	"application/octet-stream": true,
+}
+
+// ReadWorkflowSource reads ad-hoc workflow content from a local file or, when source is an
+// https:// URL, fetches it, and checks that it looks like a workflow.
+func ReadWorkflowSource(ctx context.Context, source string) ([]byte, error) {
+	var content []byte
+	var err error
+	switch {
+	case strings.HasPrefix(source, "https://"):
+		content, err = fetchWorkflowSource(ctx, source)
+	case strings.HasPrefix(source, "http://"):
+		return nil, fmt.Errorf("refusing to fetch workflow content over plain http: %s", redactWorkflowSource(source))
+	default:
+		content, err = ioutil.ReadFile(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := LintWorkflow(content); err != nil {
+		return nil, fmt.Errorf("%s: %w", redactWorkflowSource(source), err)
+	}
+	return content, nil
+}
+
+// redactWorkflowSource strips the user info, query and fragment from a workflow URL so that
+// credentials of private gists or presigned URLs are not logged or recorded in provenance.
+func redactWorkflowSource(source string) string {
+	if !strings.HasPrefix(source, "https://") && !strings.HasPrefix(source, "http://") {
+		return source
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		return strings.SplitN(strings.SplitN(source, "?", 2)[0], "#", 2)[0]
+	}
+	u.User, u.RawQuery, u.ForceQuery, u.Fragment, u.RawFragment = nil, "", false, "", ""
+	return u.String()
+}
+
+// fetchWorkflowSource downloads at most maxWorkflowSourceSize bytes of workflow content.
+func fetchWorkflowSource(ctx context.Context, source string) ([]byte, error) {
+	shown := redactWorkflowSource(source)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workflow URL %s", shown)
+	}
+	resp, err := workflowSourceClient.Do(req)
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			urlErr.URL = shown
+		}
+		return nil, fmt.Errorf("failed to fetch workflow content: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch workflow content from %s: %s", shown, resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !workflowContentTypes[mediaType] {
+			return nil, fmt.Errorf("unexpected content type '%s' for workflow content from %s", contentType, shown)
+		}
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxWorkflowSourceSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch workflow content: %w", err)
+	}
+	if len(content) > maxWorkflowSourceSize {
+		return nil, fmt.Errorf("workflow content from %s exceeds %d bytes", shown, maxWorkflowSourceSize)
+	}
+	return content, nil
+}
+
+// LintWorkflow checks that content is a GitHub Actions workflow: a YAML mapping with at least
+// one trigger under `on` and at least one job.
+func LintWorkflow(content []byte) error {
+	workflow, err := parseWorkflow(content)
+	if err != nil {
+		return err
+	}
+	if len(workflow.Triggers) == 0 {
+		return fmt.Errorf("workflow has no triggers under 'on'")
+	}
+	if len(workflow.Jobs) == 0 {
+		return fmt.Errorf("workflow has no jobs")
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// pkg/nodeprop/workflow_source_test.go
+package nodeprop
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const sourceWorkflow = "name: CI\non: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n"
+
+func TestReadWorkflowSourceFile(t *testing.T) {
+	dir := setupTempRepo(t)
+	defer teardownTempRepo(t, dir)
+
+	path := filepath.Join(dir, "ci.yml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(sourceWorkflow), 0644))
+	content, err := ReadWorkflowSource(context.Background(), path)
+	assert.NoError(t, err, "ReadWorkflowSource failed")
+	assert.Equal(t, sourceWorkflow, string(content))
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("name: CI\non: push\n"), 0644))
+	_, err = ReadWorkflowSource(context.Background(), path)
+	assert.ErrorContains(t, err, "no jobs", "Content without jobs should fail the lint")
+}
+
+func TestReadWorkflowSourceURL(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ci.yml":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte(sourceWorkflow))
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		case "/large.yml":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(strings.Repeat("#", maxWorkflowSourceSize+1)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := workflowSourceClient
+	workflowSourceClient = server.Client()
+	defer func() { workflowSourceClient = client }()
+
+	content, err := ReadWorkflowSource(context.Background(), server.URL+"/ci.yml")
+	assert.NoError(t, err, "ReadWorkflowSource failed")
+	assert.Equal(t, sourceWorkflow, string(content))
+
+	_, err = ReadWorkflowSource(context.Background(), server.URL+"/page")
+	assert.ErrorContains(t, err, "unexpected content type", "HTML pages should be rejected")
+
+	_, err = ReadWorkflowSource(context.Background(), server.URL+"/large.yml")
+	assert.ErrorContains(t, err, "exceeds", "Oversized content should be rejected")
+
+	_, err = ReadWorkflowSource(context.Background(), server.URL+"/missing.yml")
+	assert.ErrorContains(t, err, "404")
+
+	_, err = ReadWorkflowSource(context.Background(), "http://example.com/ci.yml")
+	assert.Error(t, err, "Plain http URLs should be refused")
+}
+
+func TestAddWorkflowFromURLRedactsCredentials(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(sourceWorkflow))
+	}))
+	defer server.Close()
+
+	client := workflowSourceClient
+	workflowSourceClient = server.Client()
+	defer func() { workflowSourceClient = client }()
+
+	memFS, repoPath := setupMemRepo(t)
+	npManager := &NodePropManager{
+		GlobalNodePropPath: filepath.Join("..", "..", "assets", ".empty.nodeprop.yml"),
+		Files:              memFS,
+		Logger:             logrus.New(),
+	}
+	events, unsubscribe := npManager.Subscribe()
+	defer unsubscribe()
+
+	source := strings.Replace(server.URL, "https://", "https://user:pass@", 1) + "/ci.yml?token=secret"
+	_, err := npManager.AddWorkflowWithResult(NodePropArguments{RepoPath: repoPath, Workflow: "ci", ContentSource: source})
+	assert.NoError(t, err, "AddWorkflowWithResult failed")
+
+	nodeProps, err := npManager.loadNodePropFiles(filepath.Join(repoPath, ".nodeprop.yml"))
+	assert.NoError(t, err, "Failed to read .nodeprop.yml")
+	assert.Equal(t, server.URL+"/ci.yml", nodeProps[0].Metadata.GeneratedBy.Template, "Provenance should record the URL without credentials")
+
+	for len(events) > 0 {
+		event := <-events
+		assert.NotContains(t, event.Message, "secret", "Events should not carry the query string")
+		assert.NotContains(t, event.Message, "pass", "Events should not carry user info")
+	}
+
+	_, err = ReadWorkflowSource(context.Background(), server.URL+"/ci.yml?token=wrong")
+	assert.ErrorContains(t, err, "403")
+	assert.NotContains(t, err.Error(), "wrong", "Errors should not carry the query string")
+}
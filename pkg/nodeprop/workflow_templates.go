@@ -0,0 +1,155 @@
+// pkg/nodeprop/workflow_templates.go
+package nodeprop
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Cdaprod/nodeprop/assets"
+)
+
+// Sources of a WorkflowTemplate.
+const (
+	TemplateSourceEmbedded = "embedded"
+	TemplateSourceUser     = "user"
+)
+
+// WorkflowTemplate is a named workflow template, shipped with the binary or found in the
+// configured workflow template directory.
+type WorkflowTemplate struct {
+	Name   string // file name without extension, e.g. "go-ci"
+	Source string // embedded or user
+	Path   string // file path of user templates, path inside the embedded assets otherwise
+}
+
+// ListWorkflowTemplates lists the embedded workflow templates and the .yml/.yaml templates in
+// userDir (when set), sorted by name. A user template overrides the embedded one of the same name.
+func ListWorkflowTemplates(userDir string) ([]WorkflowTemplate, error) {
+	templates := map[string]WorkflowTemplate{}
+
+	entries, err := fs.ReadDir(assets.Workflows, "workflows")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		templates[name] = WorkflowTemplate{Name: name, Source: TemplateSourceEmbedded, Path: path.Join("workflows", entry.Name())}
+	}
+
+	if userDir != "" {
+		files, err := ioutil.ReadDir(userDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workflow template directory: %w", err)
+		}
+		for _, file := range files {
+			ext := filepath.Ext(file.Name())
+			if file.IsDir() || (ext != ".yml" && ext != ".yaml") {
+				continue
+			}
+			name := strings.TrimSuffix(file.Name(), ext)
+			templates[name] = WorkflowTemplate{Name: name, Source: TemplateSourceUser, Path: filepath.Join(userDir, file.Name())}
+		}
+	}
+
+	list := make([]WorkflowTemplate, 0, len(templates))
+	for _, template := range templates {
+		list = append(list, template)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// ReadWorkflowTemplate returns the content of the named workflow template, resolved as in
+// ListWorkflowTemplates, with the include directives of user templates expanded.
+func ReadWorkflowTemplate(name, userDir string) ([]byte, WorkflowTemplate, error) {
+	templates, err := ListWorkflowTemplates(userDir)
+	if err != nil {
+		return nil, WorkflowTemplate{}, err
+	}
+
+	names := make([]string, 0, len(templates))
+	for _, template := range templates {
+		if template.Name != name {
+			names = append(names, template.Name)
+			continue
+		}
+		if template.Source == TemplateSourceEmbedded {
+			content, err := fs.ReadFile(assets.Workflows, template.Path)
+			return content, template, err
+		}
+		content, err := ioutil.ReadFile(template.Path)
+		if err != nil {
+			return nil, template, err
+		}
+		content, err = expandIncludes(content, userDir, nil)
+		if err != nil {
+			return nil, template, fmt.Errorf("workflow template '%s': %w", name, err)
+		}
+		return content, template, nil
+	}
+	return nil, WorkflowTemplate{}, fmt.Errorf("unknown workflow template '%s' (available: %s)", name, strings.Join(names, ", "))
+}
+
+// includeDirective matches a template line consisting only of `{{ include "partials/name" }}`
+// (a trailing ` .` is accepted), capturing its indentation and the partial name. Includes are
+// line-based rather than text/template actions so the workflow's own ${{ }} expressions are
+// left alone.
+var includeDirective = regexp.MustCompile(`^([ \t]*)\{\{\s*include\s+"([^"]+)"\s*\.?\s*\}\}\s*$`)
+
+// expandIncludes replaces the include directives in content with the named partials from dir,
+// each line indented like the directive. Partials may include other partials; stack holds the
+// partials being expanded, to report include cycles.
+func expandIncludes(content []byte, dir string, stack []string) ([]byte, error) {
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		match := includeDirective.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if match == nil {
+			out.WriteString(line)
+			continue
+		}
+		indent, name := match[1], match[2]
+
+		for i, including := range stack {
+			if including == name {
+				return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack[i:len(stack):len(stack)], name), " -> "))
+			}
+		}
+		partial, err := readPartial(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		partial, err = expandIncludes(partial, dir, append(stack[:len(stack):len(stack)], name))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, partialLine := range strings.Split(strings.TrimRight(string(partial), "\n"), "\n") {
+			if strings.TrimSpace(partialLine) != "" {
+				out.WriteString(indent + partialLine)
+			}
+			out.WriteString("\n")
+		}
+	}
+	return []byte(out.String()), nil
+}
+
+// readPartial reads the partial named by an include directive from dir, with or without its
+// .yml/.yaml extension.
+func readPartial(dir, name string) ([]byte, error) {
+	if !filepath.IsLocal(name) {
+		return nil, fmt.Errorf("include '%s' must be a relative path inside the workflow template directory", name)
+	}
+	for _, candidate := range []string{name, name + ".yml", name + ".yaml"} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(candidate)))
+		if err == nil {
+			return content, nil
+		}
+	}
+	return nil, fmt.Errorf("included partial '%s' not found in %s", name, dir)
+}
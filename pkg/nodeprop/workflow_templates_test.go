@@ -0,0 +1,143 @@
+// pkg/nodeprop/workflow_templates_test.go
+package nodeprop
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListWorkflowTemplates(t *testing.T) {
+	templates, err := ListWorkflowTemplates("")
+	assert.NoError(t, err, "ListWorkflowTemplates failed")
+	assert.Equal(t, []WorkflowTemplate{
+		{Name: "docker", Source: TemplateSourceEmbedded, Path: "workflows/docker.yml"},
+		{Name: "go-ci", Source: TemplateSourceEmbedded, Path: "workflows/go-ci.yml"},
+		{Name: "node-ci", Source: TemplateSourceEmbedded, Path: "workflows/node-ci.yml"},
+		{Name: "release", Source: TemplateSourceEmbedded, Path: "workflows/release.yml"},
+	}, templates, "Embedded workflow templates mismatch")
+
+	// User templates override embedded ones by name
+	userDir := setupTempRepo(t)
+	defer teardownTempRepo(t, userDir)
+	for _, name := range []string{"go-ci.yml", "custom.yaml", "notes.txt"} {
+		err = ioutil.WriteFile(filepath.Join(userDir, name), []byte("name: User\non: push\njobs: {}\n"), 0644)
+		assert.NoError(t, err, "Failed to write %s", name)
+	}
+
+	templates, err = ListWorkflowTemplates(userDir)
+	assert.NoError(t, err, "ListWorkflowTemplates failed")
+	assert.Equal(t, []WorkflowTemplate{
+		{Name: "custom", Source: TemplateSourceUser, Path: filepath.Join(userDir, "custom.yaml")},
+		{Name: "docker", Source: TemplateSourceEmbedded, Path: "workflows/docker.yml"},
+		{Name: "go-ci", Source: TemplateSourceUser, Path: filepath.Join(userDir, "go-ci.yml")},
+		{Name: "node-ci", Source: TemplateSourceEmbedded, Path: "workflows/node-ci.yml"},
+		{Name: "release", Source: TemplateSourceEmbedded, Path: "workflows/release.yml"},
+	}, templates, "User workflow templates should override embedded ones")
+
+	content, template, err := ReadWorkflowTemplate("go-ci", userDir)
+	assert.NoError(t, err, "ReadWorkflowTemplate failed")
+	assert.Equal(t, TemplateSourceUser, template.Source, "User template should be read")
+	assert.Equal(t, "name: User\non: push\njobs: {}\n", string(content), "User template content mismatch")
+
+	_, _, err = ReadWorkflowTemplate("missing", userDir)
+	assert.ErrorContains(t, err, "unknown workflow template 'missing'", "Expected an error for an unknown template")
+}
+
+func TestEmbeddedWorkflowTemplates(t *testing.T) {
+	templates, err := ListWorkflowTemplates("")
+	assert.NoError(t, err, "ListWorkflowTemplates failed")
+
+	policy := WorkflowPolicy{EnforcePermissions: true, EnforceConcurrency: true}
+	for _, template := range templates {
+		t.Run(template.Name, func(t *testing.T) {
+			content, _, err := ReadWorkflowTemplate(template.Name, "")
+			assert.NoError(t, err, "ReadWorkflowTemplate failed")
+
+			rendered, _, err := ApplyWorkflowPolicy(content, policy, "example", template.Name)
+			assert.NoError(t, err, "ApplyWorkflowPolicy failed")
+
+			workflow, err := parseWorkflow(rendered)
+			assert.NoError(t, err, "Rendered template should parse")
+			assert.NotEmpty(t, workflow.Name, "Template should be named")
+			assert.NotEmpty(t, workflow.Triggers, "Template should declare triggers")
+			assert.NotEmpty(t, workflow.Jobs, "Template should declare jobs")
+		})
+	}
+}
+
+func TestReadWorkflowTemplateIncludes(t *testing.T) {
+	userDir := setupTempRepo(t)
+	defer teardownTempRepo(t, userDir)
+	partialsDir := filepath.Join(userDir, "partials")
+	assert.NoError(t, os.MkdirAll(partialsDir, 0755), "Failed to create partials directory")
+
+	files := map[string]string{
+		"ci.yml": `name: CI
+on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      {{ include "partials/setup-go" . }}
+      - run: go test ./...
+`,
+		"partials/setup-go.yml": `{{ include "partials/checkout" }}
+
+- uses: actions/setup-go@v5
+  with:
+    go-version: ${{ matrix.go }}
+`,
+		"partials/checkout.yml": "- uses: actions/checkout@v4\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(userDir, name), []byte(content), 0644), "Failed to write %s", name)
+	}
+
+	content, _, err := ReadWorkflowTemplate("ci", userDir)
+	assert.NoError(t, err, "ReadWorkflowTemplate failed")
+	assert.Equal(t, `name: CI
+on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+
+      - uses: actions/setup-go@v5
+        with:
+          go-version: ${{ matrix.go }}
+      - run: go test ./...
+`, string(content), "Nested partials should be expanded with the directive's indentation")
+
+	templates, err := ListWorkflowTemplates(userDir)
+	assert.NoError(t, err, "ListWorkflowTemplates failed")
+	for _, template := range templates {
+		assert.NotContains(t, template.Name, "setup-go", "Partials should not be listed as templates")
+	}
+}
+
+func TestReadWorkflowTemplateIncludeCycle(t *testing.T) {
+	userDir := setupTempRepo(t)
+	defer teardownTempRepo(t, userDir)
+	assert.NoError(t, os.MkdirAll(filepath.Join(userDir, "partials"), 0755), "Failed to create partials directory")
+
+	files := map[string]string{
+		"ci.yml":         "steps:\n  {{ include \"partials/a\" }}\n",
+		"partials/a.yml": "{{ include \"partials/b\" }}\n",
+		"partials/b.yml": "{{ include \"partials/a\" }}\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(userDir, name), []byte(content), 0644), "Failed to write %s", name)
+	}
+
+	_, _, err := ReadWorkflowTemplate("ci", userDir)
+	assert.ErrorContains(t, err, "include cycle: partials/a -> partials/b -> partials/a", "The cycle should be named")
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(userDir, "ci.yml"), []byte("{{ include \"partials/missing\" }}\n"), 0644))
+	_, _, err = ReadWorkflowTemplate("ci", userDir)
+	assert.ErrorContains(t, err, "included partial 'partials/missing' not found", "Missing partials should be reported")
+}
@@ -0,0 +1,267 @@
+// pkg/nodeprop/workflow_test.go
+package nodeprop
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+const policyTestWorkflow = `name: CI
+
+on:
+  push:
+    branches:
+      - main
+
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v3
+`
+
+func TestApplyWorkflowPolicyInjectsMissingBlocks(t *testing.T) {
+	policy := WorkflowPolicy{
+		EnforcePermissions: true,
+		EnforceConcurrency: true,
+	}
+
+	content, injected, err := ApplyWorkflowPolicy([]byte(policyTestWorkflow), policy, "nodeprop", "ci")
+	assert.NoError(t, err, "ApplyWorkflowPolicy failed")
+	assert.Equal(t, []string{"permissions", "concurrency"}, injected, "Both blocks should be injected")
+
+	var workflow struct {
+		Permissions map[string]string `yaml:"permissions"`
+		Concurrency struct {
+			Group string `yaml:"group"`
+		} `yaml:"concurrency"`
+		Jobs map[string]interface{} `yaml:"jobs"`
+	}
+	err = yaml.Unmarshal(content, &workflow)
+	assert.NoError(t, err, "Injected workflow should still be valid YAML")
+	assert.Equal(t, map[string]string{"contents": "read"}, workflow.Permissions, "Default permissions mismatch")
+	assert.Equal(t, "nodeprop-ci-${{ github.ref }}", workflow.Concurrency.Group, "Concurrency group mismatch")
+	assert.Contains(t, workflow.Jobs, "build", "Jobs should be preserved")
+}
+
+func TestApplyWorkflowPolicyKeepsExplicitDeclarations(t *testing.T) {
+	explicit := "permissions:\n  contents: write\n\n" + policyTestWorkflow
+	policy := WorkflowPolicy{
+		EnforcePermissions: true,
+		DefaultPermissions: map[string]string{"contents": "read", "packages": "read"},
+	}
+
+	content, injected, err := ApplyWorkflowPolicy([]byte(explicit), policy, "nodeprop", "ci")
+	assert.NoError(t, err, "ApplyWorkflowPolicy failed")
+	assert.Empty(t, injected, "Explicit permissions should not be overridden")
+	assert.Equal(t, explicit, string(content), "Workflow content should be unchanged")
+}
+
+func TestApplyWorkflowPolicyDisabled(t *testing.T) {
+	content, injected, err := ApplyWorkflowPolicy([]byte(policyTestWorkflow), WorkflowPolicy{}, "nodeprop", "ci")
+	assert.NoError(t, err, "ApplyWorkflowPolicy failed")
+	assert.Empty(t, injected, "Nothing should be injected when the policy is disabled")
+	assert.Equal(t, policyTestWorkflow, string(content), "Workflow content should be unchanged")
+}
+
+func TestNormalizeWorkflowContent(t *testing.T) {
+	content := NormalizeWorkflowContent([]byte("name: CI  \r\non: push\t\r\njobs:\r  build: {}\n\n\n"))
+	assert.Equal(t, "name: CI\non: push\njobs:\n  build: {}\n", string(content), "Workflow content should be normalized")
+
+	content = NormalizeWorkflowContent([]byte("name: CI"))
+	assert.Equal(t, "name: CI\n", string(content), "A missing trailing newline should be added")
+}
+
+func TestDiscoverWorkflows(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	workflowsDir := filepath.Join(repoPath, ".github", "workflows")
+	err := os.MkdirAll(workflowsDir, 0755)
+	assert.NoError(t, err, "Failed to create workflows directory")
+
+	files := map[string]string{
+		// Anchors, merge keys and a reusable workflow job
+		"ci.yml": `name: CI
+on:
+  push:
+    branches: &branches [main]
+  pull_request:
+    branches: *branches
+x-defaults: &defaults
+  runs-on: ubuntu-latest
+jobs:
+  test:
+    <<: *defaults
+    steps:
+      - run: go test ./...
+  deploy:
+    uses: Cdaprod/workflows/.github/workflows/deploy.yml@main
+    secrets: inherit
+`,
+		"nightly.yaml": `on: [schedule, workflow_dispatch]
+jobs:
+  build:
+    runs-on: ubuntu-latest
+`,
+		"release.yml": `name: Release
+"on": release
+jobs: {}
+`,
+		"broken.yml": "name: [unterminated\n",
+		"README.md":  "not a workflow\n",
+	}
+	for name, content := range files {
+		err = ioutil.WriteFile(filepath.Join(workflowsDir, name), []byte(content), 0644)
+		assert.NoError(t, err, "Failed to write %s", name)
+	}
+
+	workflows, err := DiscoverWorkflows(repoPath, "", "https://github.com/Cdaprod/example")
+	assert.ErrorContains(t, err, "broken.yml", "Expected the malformed workflow to be reported")
+	assert.Equal(t, []Workflow{
+		{
+			Name:     "CI",
+			File:     ".github/workflows/ci.yml",
+			Triggers: []string{"pull_request", "push"},
+			Jobs:     []string{"deploy", "test"},
+			Badge:    "https://github.com/Cdaprod/example/actions/workflows/ci.yml/badge.svg",
+		},
+		{
+			Name:     "nightly.yaml",
+			File:     ".github/workflows/nightly.yaml",
+			Triggers: []string{"schedule", "workflow_dispatch"},
+			Jobs:     []string{"build"},
+			Badge:    "https://github.com/Cdaprod/example/actions/workflows/nightly.yaml/badge.svg",
+		},
+		{
+			Name:     "Release",
+			File:     ".github/workflows/release.yml",
+			Triggers: []string{"release"},
+			Jobs:     []string{},
+			Badge:    "https://github.com/Cdaprod/example/actions/workflows/release.yml/badge.svg",
+		},
+	}, workflows, "Discovered workflows mismatch")
+}
+
+func TestDiscoverWorkflowsWithoutDirectory(t *testing.T) {
+	repoPath := setupTempRepo(t)
+	defer teardownTempRepo(t, repoPath)
+
+	workflows, err := DiscoverWorkflows(repoPath, "", "https://github.com/Cdaprod/example")
+	assert.NoError(t, err, "DiscoverWorkflows failed")
+	assert.Empty(t, workflows, "No workflows should be discovered")
+}
+
+func TestWorkflowBadgeMarkdown(t *testing.T) {
+	workflow := Workflow{Name: "Build and Test", File: ".github/workflows/ci.yml"}
+	assert.Equal(t,
+		"[![Build and Test](https://img.shields.io/github/actions/workflow/status/Cdaprod/example/ci.yml?label=Build%20and%20Test)](https://github.com/Cdaprod/example/actions/workflows/ci.yml)",
+		workflow.BadgeMarkdown("https://github.com/Cdaprod/example"),
+		"Badge markdown mismatch")
+}
+
+const diffTestWorkflow = `name: CI
+on: [push, pull_request]
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - name: Test
+        run: go test ./...
+`
+
+func TestDiffWorkflowIgnoresFormatting(t *testing.T) {
+	npManager := &NodePropManager{}
+
+	reformatted := `# Reformatted by hand
+name: "CI"
+"on":
+  pull_request:
+  push:
+jobs:
+  build:
+    steps:
+    - {uses: "actions/checkout@v4"}
+    - run: go test ./...
+      name: Test
+    runs-on: ubuntu-latest
+`
+	changes, err := npManager.DiffWorkflow(diffTestWorkflow, reformatted)
+	assert.NoError(t, err, "DiffWorkflow failed")
+	assert.Empty(t, changes, "Reformatted but equivalent workflows should not differ")
+}
+
+func TestDiffWorkflowReportsChanges(t *testing.T) {
+	npManager := &NodePropManager{}
+
+	local := `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-22.04
+    steps:
+      - uses: actions/checkout@v4
+      - name: Test
+        run: go test -race ./...
+  lint:
+    runs-on: ubuntu-latest
+`
+	changes, err := npManager.DiffWorkflow(local, diffTestWorkflow)
+	assert.NoError(t, err, "DiffWorkflow failed")
+	assert.Equal(t, []WorkflowChange{
+		{Path: "jobs.build.runs-on", Kind: WorkflowChangeChanged, Old: "ubuntu-latest", New: "ubuntu-22.04"},
+		{Path: "jobs.build.steps[1].run", Kind: WorkflowChangeChanged, Old: "go test ./...", New: "go test -race ./..."},
+		{Path: "jobs.lint", Kind: WorkflowChangeAdded, New: "runs-on: ubuntu-latest"},
+		{Path: "on.pull_request", Kind: WorkflowChangeRemoved},
+	}, changes, "Workflow changes mismatch")
+
+	_, err = npManager.DiffWorkflow("jobs: [unterminated", diffTestWorkflow)
+	assert.Error(t, err, "Expected an error for a malformed workflow")
+}
+
+func TestApplyWorkflowPolicyQuotesConcurrencyGroup(t *testing.T) {
+	policy := WorkflowPolicy{EnforceConcurrency: true}
+
+	for _, workflow := range []string{"ci #1", "deploy: prod", `say "hi"`} {
+		content, injected, err := ApplyWorkflowPolicy([]byte(policyTestWorkflow), policy, "my-repo", workflow)
+		assert.NoError(t, err, "ApplyWorkflowPolicy failed for %q", workflow)
+		assert.Equal(t, []string{"concurrency"}, injected)
+
+		var parsed struct {
+			Concurrency struct {
+				Group string `yaml:"group"`
+			} `yaml:"concurrency"`
+		}
+		assert.NoError(t, yaml.Unmarshal(content, &parsed), "Injected workflow should still be valid YAML")
+		assert.Equal(t, "my-repo-"+workflow+"-${{ github.ref }}", parsed.Concurrency.Group, "The group should survive special characters")
+	}
+}
+
+func TestApplyWorkflowPolicyFlowStyle(t *testing.T) {
+	flow := "# CI in flow style\n{name: CI, on: push, jobs: {build: {runs-on: ubuntu-latest}}}\n"
+	policy := WorkflowPolicy{EnforcePermissions: true, EnforceConcurrency: true}
+
+	content, injected, err := ApplyWorkflowPolicy([]byte(flow), policy, "nodeprop", "ci #1")
+	assert.NoError(t, err, "ApplyWorkflowPolicy failed")
+	assert.Equal(t, []string{"permissions", "concurrency"}, injected, "Both blocks should be injected")
+	assert.True(t, strings.HasPrefix(string(content), "# CI in flow style\n{name: CI, on: push,"), "The workflow should not be re-encoded")
+
+	var workflow struct {
+		Permissions map[string]string `yaml:"permissions"`
+		Concurrency struct {
+			Group string `yaml:"group"`
+		} `yaml:"concurrency"`
+		Jobs map[string]interface{} `yaml:"jobs"`
+	}
+	assert.NoError(t, yaml.Unmarshal(content, &workflow), "Injected workflow should still be valid YAML")
+	assert.Equal(t, map[string]string{"contents": "read"}, workflow.Permissions)
+	assert.Equal(t, "nodeprop-ci #1-${{ github.ref }}", workflow.Concurrency.Group)
+	assert.Contains(t, workflow.Jobs, "build", "Jobs should be preserved")
+}